@@ -0,0 +1,97 @@
+package minimalmux
+
+import "net/http"
+
+// Group registers routes on a ServeMux under a shared prefix,
+// wrapping every handler with the middlewares of the group.
+type Group struct {
+	mux         *ServeMux
+	prefix      string
+	middlewares Middlewares
+}
+
+// Group creates a group whose routes are prefixed with prefix, and calls fn with it.
+func (sm *ServeMux) Group(prefix string, fn func(g *Group)) *Group {
+	g := &Group{mux: sm, prefix: prefix}
+	if fn != nil {
+		fn(g)
+	}
+	return g
+}
+
+// Group creates a nested group which inherits the prefix and the middlewares
+// of g, and calls fn with it.
+func (g *Group) Group(prefix string, fn func(g *Group)) *Group {
+	child := &Group{
+		mux:         g.mux,
+		prefix:      g.prefix + prefix,
+		middlewares: append(Middlewares{}, g.middlewares...),
+	}
+	if fn != nil {
+		fn(child)
+	}
+	return child
+}
+
+// Use appends middlewares to the group.
+// They apply to the routes registered after the call.
+func (g *Group) Use(middlewares ...func(http.Handler) http.Handler) *Group {
+	for _, m := range middlewares {
+		g.middlewares.Append(m)
+	}
+	return g
+}
+
+func (g *Group) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	g.handle(methodAll, pattern, handler)
+}
+
+func (g *Group) Handle(pattern string, handler http.Handler) {
+	g.handle(methodAll, pattern, handler.ServeHTTP)
+}
+
+func (g *Group) Get(path string, handler http.HandlerFunc) *RouteRef {
+	return g.handle(http.MethodGet, path, handler)
+}
+
+func (g *Group) Post(path string, handler http.HandlerFunc) *RouteRef {
+	return g.handle(http.MethodPost, path, handler)
+}
+
+func (g *Group) Put(path string, handler http.HandlerFunc) *RouteRef {
+	return g.handle(http.MethodPut, path, handler)
+}
+
+func (g *Group) Delete(path string, handler http.HandlerFunc) *RouteRef {
+	return g.handle(http.MethodDelete, path, handler)
+}
+
+func (g *Group) Head(path string, handler http.HandlerFunc) *RouteRef {
+	return g.handle(http.MethodHead, path, handler)
+}
+
+func (g *Group) Options(path string, handler http.HandlerFunc) *RouteRef {
+	return g.handle(http.MethodOptions, path, handler)
+}
+
+func (g *Group) Patch(path string, handler http.HandlerFunc) *RouteRef {
+	return g.handle(http.MethodPatch, path, handler)
+}
+
+// handle registers handler for method under the prefix of the group
+func (g *Group) handle(method string, pattern string, handler func(http.ResponseWriter, *http.Request)) *RouteRef {
+	route := Route{
+		Pattern:         g.prefix + pattern,
+		HandlerFunc:     handler,
+		MiddlewareCount: len(g.middlewares),
+	}
+	if handler != nil && len(g.middlewares) > 0 {
+		// the middlewares added to the group later do not apply to the route
+		ms := append(Middlewares{}, g.middlewares...)
+		route.wrap = func(h http.HandlerFunc) http.HandlerFunc {
+			return ms.Handle(h).ServeHTTP
+		}
+		route.HandlerFunc = route.wrap(handler)
+	}
+	return g.mux.register(method, route)
+}
@@ -0,0 +1,77 @@
+package minimalmux
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	mux := NewServeMux()
+	mux.Get("/", testHandler)
+	mux.Group("/api", func(g *Group) {
+		g.Use(testMiddleware("api"))
+		g.Get("/users", testHandler)
+		g.Post("/users", testHandler)
+
+		g.Group("/admin", func(g *Group) {
+			g.Use(testMiddleware("admin"))
+			g.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("user " + GetParams(r)["id"] + "\n"))
+			})
+		})
+
+		g.Get("/health", testHandler)
+	})
+	mux.Group("/v2", nil).HandleFunc("/any", testHandler)
+
+	ts := httptest.NewServer(mux)
+
+	tcs := []struct {
+		method       string
+		path         string
+		expectStatus int
+		expectBody   string
+	}{
+		{method: http.MethodGet, path: "/", expectStatus: http.StatusOK, expectBody: "test\n"},
+		{method: http.MethodGet, path: "/api/users", expectStatus: http.StatusOK, expectBody: "api\ntest\n"},
+		{method: http.MethodPost, path: "/api/users", expectStatus: http.StatusOK, expectBody: "api\ntest\n"},
+		{method: http.MethodGet, path: "/api/admin/users/42", expectStatus: http.StatusOK, expectBody: "api\nadmin\nuser 42\n"},
+		{method: http.MethodGet, path: "/api/health", expectStatus: http.StatusOK, expectBody: "api\ntest\n"},
+		{method: http.MethodPut, path: "/v2/any", expectStatus: http.StatusOK, expectBody: "test\n"},
+		{method: http.MethodGet, path: "/users", expectStatus: http.StatusNotFound, expectBody: "404 page not found\n"},
+	}
+	for _, tc := range tcs {
+		res := testHttpRequest(t, ts, tc.method, tc.path)
+		if res.StatusCode != tc.expectStatus {
+			t.Errorf("%s %s: Status code not equal. got: %d, want: %d", tc.method, tc.path, res.StatusCode, tc.expectStatus)
+		}
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != tc.expectBody {
+			t.Errorf("%s %s: Response body not equal. got: %q, want: %q", tc.method, tc.path, string(body), tc.expectBody)
+		}
+	}
+}
+
+func TestGroupReplace(t *testing.T) {
+	mux := NewServeMux()
+	mux.Group("/admin", func(g *Group) {
+		g.Use(testMiddleware("auth"))
+		g.Get("/users", testHandler)
+		// added after the route, so it does not apply to the route
+		g.Use(testMiddleware("later"))
+	})
+
+	testEqual(t, mux.Replace(http.MethodGet, "/admin/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("replaced\n"))
+	}), true)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+	testEqual(t, w.Body.String(), "auth\nreplaced\n")
+	testEqual(t, mux.Routes()[0].MiddlewareCount, 1)
+}
@@ -0,0 +1,52 @@
+package minimalmux
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// headHandler serves a HEAD request with the handler of the GET route.
+// the body written by h is discarded, and its length is reported as Content-Length.
+func headHandler(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hw := &headResponseWriter{ResponseWriter: w}
+		h(hw, r)
+		hw.finish()
+	}
+}
+
+// headResponseWriter holds the status code back until the handler returns,
+// so that Content-Length can be computed from the discarded body.
+type headResponseWriter struct {
+	http.ResponseWriter
+	status int
+	length int
+}
+
+func (w *headResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *headResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.length += len(p)
+	return len(p), nil
+}
+
+func (w *headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *headResponseWriter) finish() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.length > 0 && w.Header().Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(w.length))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
@@ -0,0 +1,57 @@
+package minimalmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeadFallbackToGet(t *testing.T) {
+	mux := NewServeMux()
+	mux.Get("/healthcheck", HealthcheckHandler)
+	mux.Get("/explicit", testHandler)
+	mux.Head("/explicit", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Explicit", "true")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.Post("/post", testHandler)
+
+	t.Run("fallback to GET route", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodHead, "/healthcheck", nil)
+		mux.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Status code not equal. got: %d, want: %d", w.Code, http.StatusOK)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("body must be discarded. got: %q", w.Body.String())
+		}
+		if cl := w.Header().Get("Content-Length"); cl != "2" {
+			t.Errorf("Content-Length not equal. got: %s, want: %s", cl, "2")
+		}
+	})
+
+	t.Run("explicit HEAD route", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodHead, "/explicit", nil)
+		mux.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("Status code not equal. got: %d, want: %d", w.Code, http.StatusNoContent)
+		}
+		if w.Header().Get("X-Explicit") != "true" {
+			t.Errorf("explicit HEAD handler is not called")
+		}
+	})
+
+	t.Run("no GET route", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodHead, "/post", nil)
+		mux.ServeHTTP(w, r)
+
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Status code not equal. got: %d, want: %d", w.Code, http.StatusMethodNotAllowed)
+		}
+	})
+}
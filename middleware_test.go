@@ -93,3 +93,53 @@ func TestMiddlewaresHandleFunc(t *testing.T) {
 		t.Fatalf("expected %q, got %q", expected, w.Body.String())
 	}
 }
+
+func TestServeMuxUse(t *testing.T) {
+	var got []string
+	labelMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = append(got, r.Method+" "+GetPattern(r)+" id="+GetParams(r)["id"])
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	mux := NewServeMux()
+	mux.Use(labelMiddleware, testMiddleware("a"))
+	mux.Get("/users/:id", testHandler)
+
+	for _, path := range []string{"/users/1", "/users/2", "/unknown"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		mux.ServeHTTP(w, r)
+	}
+
+	expected := []string{
+		"GET /users/:id id=1",
+		"GET /users/:id id=2",
+		"GET  id=",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], got[i])
+		}
+	}
+}
+
+func TestServeMuxUseAfterRegistration(t *testing.T) {
+	mux := NewServeMux()
+	mux.Get("/users/:id", testHandler)
+	mux.Use(testMiddleware("a"))
+	mux.Put("/users/:id", testHandler)
+	mux.Use(testMiddleware("b"))
+	mux.Replace(http.MethodPut, "/users/:id", testHandler)
+
+	// the middlewares apply to the routes registered before and after Use
+	for _, method := range []string{http.MethodGet, http.MethodPut} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, "/users/1", nil))
+		testEqual(t, w.Body.String(), "a\nb\ntest\n")
+	}
+}
@@ -6,22 +6,50 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
 type ServeMux struct {
-	tree            *Node
-	mu              sync.RWMutex
-	notFoundHandler http.HandlerFunc
+	// tree and middlewares are immutable once published, so that lookups
+	// need no lock. writers build new ones under mu and swap them.
+	tree                    atomic.Pointer[Node]
+	middlewares             atomic.Pointer[Middlewares]
+	mu                      sync.Mutex
+	notFoundHandler         http.HandlerFunc
+	methodNotAllowedHandler http.HandlerFunc
+	trailingSlash           TrailingSlash
+	cleanPath               bool
+	rawPath                 bool
+	caseInsensitive         bool
+	caseRedirect            bool
+	names                   map[string]string // route name -> pattern, guarded by mu
 }
 
 type Route struct {
 	Method       string
 	Pattern      string
+	Name         string
 	HandlerFunc  http.HandlerFunc
 	PathParamMap map[string]string
+	// MiddlewareCount is the number of middlewares wrapping HandlerFunc at registration,
+	// such as the ones of Group. the ones of ServeMux.Use are not counted.
+	MiddlewareCount int
+
+	// wrap applies the middlewares counted by MiddlewareCount to a handler given to Replace
+	wrap func(http.HandlerFunc) http.HandlerFunc
+
+	// mount is true for a route registered by Mount
+	mount bool
+
+	// handler is HandlerFunc wrapped by the middlewares of ServeMux.Use,
+	// built when the route or the middlewares are published
+	handler http.Handler
 }
 
 func (r *Route) IsBlank() bool {
@@ -32,13 +60,57 @@ func (r *Route) setPathParams(pathParamMap map[string]string) {
 	r.PathParamMap = pathParamMap
 }
 
-func NewServeMux() *ServeMux {
-	return &ServeMux{
-		tree:            &Node{},
-		notFoundHandler: http.NotFound,
+func NewServeMux(opts ...Option) *ServeMux {
+	sm := &ServeMux{
+		notFoundHandler:         http.NotFound,
+		methodNotAllowedHandler: methodNotAllowed,
+	}
+	sm.tree.Store(&Node{})
+	for _, opt := range opts {
+		opt(sm)
+	}
+	return sm
+}
+
+// emptyTree is used by a zero value ServeMux which has no route yet
+var emptyTree = &Node{}
+
+// loadTree returns the currently published tree
+func (sm *ServeMux) loadTree() *Node {
+	if tree := sm.tree.Load(); tree != nil {
+		return tree
+	}
+	return emptyTree
+}
+
+// wrap applies the currently published middlewares of the mux to h
+func (sm *ServeMux) wrap(h http.Handler) http.Handler {
+	if ms := sm.middlewares.Load(); ms != nil {
+		return ms.Handle(h)
+	}
+	return h
+}
+
+// withAllow sets the Allow header to methods before calling h
+func withAllow(methods []string, h http.HandlerFunc) http.HandlerFunc {
+	allow := strings.Join(methods, ", ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		h(w, r)
 	}
 }
 
+// automaticOptions replies to an OPTIONS request for a path which has no
+// explicit OPTIONS route. the Allow header is set by withAllow.
+func automaticOptions(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// methodNotAllowed replies to the request with an HTTP 405 method not allowed error.
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
 const methodAll = "_all"
 
 var methodSlice = []string{
@@ -60,28 +132,176 @@ func (sm *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter,
 
 type paramCtxKey int
 
-const paramMapKey paramCtxKey = iota
+const (
+	paramMapKey paramCtxKey = iota
+	routeKey
+	mountPathKey
+	mountRestKey
+)
 
 func (sm *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, req := sm.dispatch(r)
+	h.ServeHTTP(w, req)
+}
+
+// dispatch looks up the handler for r, wrapped by the middlewares of the mux.
+// the returned request carries the matched route and path params.
+func (sm *ServeMux) dispatch(r *http.Request) (http.Handler, *http.Request) {
+	tree := sm.loadTree()
 	path := r.URL.Path
-	route := sm.tree.search(r.Method, path)
+	if sm.rawPath {
+		path = r.URL.EscapedPath()
+	}
+	if sm.cleanPath {
+		if clean := cleanPath(path); clean != path {
+			// safe methods are redirected, and the others are routed on the clean path
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				return sm.wrap(redirectTo(clean, sm.rawPath)), r
+			}
+			path = clean
+		}
+	}
+	fold := sm.caseInsensitive
+	route, canonical := match(tree, r.Method, path, fold)
+	if route.IsBlank() && sm.trailingSlash != TrailingSlashStrict && len(tree.allowedMethods(path, fold)) == 0 {
+		// path is registered with or without the trailing slash
+		if alt, ok := toggleTrailingSlash(path); ok && len(tree.allowedMethods(alt, fold)) > 0 {
+			if sm.trailingSlash == TrailingSlashRedirect {
+				if fold && sm.caseRedirect {
+					// redirect to the registered case at once
+					if _, c := match(tree, r.Method, alt, fold); c != "" {
+						alt = c
+					}
+				}
+				return sm.wrap(redirectTo(alt, sm.rawPath)), r
+			}
+			path = alt
+			route, canonical = match(tree, r.Method, path, fold)
+		}
+	}
+	if !route.IsBlank() && sm.caseRedirect && canonical != path {
+		return sm.wrap(redirectTo(canonical, sm.rawPath)), r
+	}
 	if route.IsBlank() {
-		sm.notFoundHandler(w, r)
-		return
+		// path exists for another method
+		if allow := tree.allowedMethods(path, fold); len(allow) > 0 {
+			// HEAD is answered by GET route, and OPTIONS is always answered,
+			// explicitly or automatically
+			if slices.Contains(allow, http.MethodGet) && !slices.Contains(allow, http.MethodHead) {
+				allow = append(allow, http.MethodHead)
+			}
+			if !slices.Contains(allow, http.MethodOptions) {
+				allow = append(allow, http.MethodOptions)
+			}
+			sort.Strings(allow)
+			if r.Method == http.MethodOptions {
+				return sm.wrap(withAllow(allow, automaticOptions)), r
+			}
+			return sm.wrap(withAllow(allow, sm.methodNotAllowedHandler)), r
+		}
+		return sm.wrap(sm.notFoundHandler), r
+	}
+	params := route.PathParamMap
+	if sm.rawPath {
+		unescapeParams(params)
 	}
-	ctx := context.WithValue(r.Context(), paramMapKey, route.PathParamMap)
-	req := r.WithContext(ctx)
-	route.HandlerFunc(w, req)
+	// merge path params of the parent mux when this mux is mounted
+	if parent := GetParams(r); len(parent) > 0 {
+		params = make(map[string]string, len(parent)+len(route.PathParamMap))
+		for k, v := range parent {
+			params[k] = v
+		}
+		for k, v := range route.PathParamMap {
+			params[k] = v
+		}
+	}
+	ctx := r.Context()
+	if rest, ok := params[mountParamKey]; ok && route.mount {
+		// the path below a mount prefix is kept apart from the path params
+		delete(params, mountParamKey)
+		ctx = context.WithValue(ctx, mountRestKey, rest)
+	}
+	route.setPathParams(params)
+	ctx = context.WithValue(ctx, paramMapKey, params)
+	ctx = context.WithValue(ctx, routeKey, route)
+	h := route.handler
+	if h == nil {
+		// the GET route serving a HEAD request
+		h = sm.wrap(route.HandlerFunc)
+	}
+	return h, r.WithContext(ctx)
+}
+
+// match finds the route of method and path in tree, with path in the registered case.
+// a HEAD request falls back to the GET route without body.
+func match(tree *Node, method, path string, fold bool) (Route, string) {
+	route, canonical := tree.find(method, path, fold)
+	if route.IsBlank() && method == http.MethodHead {
+		if route, canonical = tree.find(http.MethodGet, path, fold); !route.IsBlank() {
+			route.HandlerFunc = headHandler(route.HandlerFunc)
+			route.handler = nil
+		}
+	}
+	return route, canonical
 }
 
 func (sm *ServeMux) Handle(pattern string, handler http.Handler) {
 	sm.handle(methodAll, pattern, handler.ServeHTTP)
 }
 
+// Handler returns the handler which ServeHTTP uses for r, and the pattern of the matched route.
+// The pattern is "" if no route matches, such as when r is redirected or not found.
+// The handler serves with the path params of r.
 func (sm *ServeMux) Handler(r *http.Request) (h http.Handler, pattern string) {
-	path := r.URL.Path
-	route := sm.tree.search(r.Method, path)
-	return route.HandlerFunc, route.Pattern
+	handler, req := sm.dispatch(r)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(req.Context()))
+	}), GetPattern(req)
+}
+
+// mountParamKey is the key of the catch-all param which holds the path below a mount prefix
+const mountParamKey = ""
+
+// Mount delegates every request under prefix to handler, with prefix stripped from r.URL.Path.
+// path params of prefix are available from GetParams in handler, and
+// are merged into the params of handler if it is a *ServeMux.
+// The routes of a mount are not listed by Routes and Walk, and
+// GetPattern reports prefix, or prefix followed by "/*", in the middlewares of the mux.
+func (sm *ServeMux) Mount(prefix string, handler http.Handler) {
+	if handler == nil {
+		panic("http: nil handler")
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	mount := func(w http.ResponseWriter, r *http.Request) {
+		params := map[string]string{}
+		for k, v := range GetParams(r) {
+			params[k] = v
+		}
+		rest, _ := r.Context().Value(mountRestKey).(string)
+		u := *r.URL
+		u.Path = "/" + rest
+		// keep the escaping of the path for handler routing on the escaped path
+		u.RawPath = escapedSuffix(r.URL.EscapedPath(), u.Path)
+
+		ctx := context.WithValue(r.Context(), paramMapKey, params)
+		ctx = context.WithValue(ctx, mountPathKey, mountPath(r)+strings.TrimSuffix(r.URL.Path, u.Path))
+		req := r.WithContext(ctx)
+		req.URL = &u
+		handler.ServeHTTP(w, req)
+	}
+
+	if prefix != "" {
+		sm.register(methodAll, Route{Pattern: prefix, HandlerFunc: mount, mount: true})
+	}
+	sm.register(methodAll, Route{Pattern: prefix + "/*" + mountParamKey, HandlerFunc: mount, mount: true})
+}
+
+// mountPath returns the path which r.URL.Path is mounted under, or "" if r is not mounted.
+// it includes the prefixes of every enclosing mount.
+func mountPath(r *http.Request) string {
+	path, _ := r.Context().Value(mountPathKey).(string)
+	return path
 }
 
 func GetParams(r *http.Request) map[string]string {
@@ -91,88 +311,192 @@ func GetParams(r *http.Request) map[string]string {
 	return nil
 }
 
-// original method
+// GetRoute returns the route matched to r, with the path params of r.
+// It returns false if no route is matched.
+func GetRoute(r *http.Request) (Route, bool) {
+	route, ok := r.Context().Value(routeKey).(Route)
+	return route, ok
+}
+
+// GetPattern returns the pattern of the route matched to r, such as "/users/:id".
+// It returns "" if no route is matched.
+func GetPattern(r *http.Request) string {
+	route, _ := GetRoute(r)
+	return route.Pattern
+}
 
-func (sm *ServeMux) handle(method string, pattern string, handler func(http.ResponseWriter, *http.Request)) {
+// Use appends middlewares to the mux.
+// They run after routing, so GetParams and GetPattern are available in them.
+// They also wrap the not found and method not allowed handlers.
+func (sm *ServeMux) Use(middlewares ...func(http.Handler) http.Handler) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	ms := NewMiddlewares()
+	if old := sm.middlewares.Load(); old != nil {
+		*ms = append(*ms, *old...)
+	}
+	for _, m := range middlewares {
+		ms.Append(m)
+	}
+	sm.middlewares.Store(ms)
+
+	// rebuild the handlers of the routes with the new middlewares
+	tree := sm.loadTree().clone()
+	tree.eachRoute(func(route *Route) {
+		route.handler = ms.Handle(route.HandlerFunc)
+	})
+	sm.tree.Store(tree)
+}
+
+// original method
+
+func (sm *ServeMux) handle(method string, pattern string, handler func(http.ResponseWriter, *http.Request)) *RouteRef {
+	return sm.register(method, Route{Pattern: pattern, HandlerFunc: handler})
+}
+
+// register registers route for method, or for every method if method is methodAll.
+func (sm *ServeMux) register(method string, route Route) *RouteRef {
 	if method == "" {
 		panic("http: invalid method")
 	}
-	if handler == nil {
+	if route.HandlerFunc == nil {
 		panic("http: nil handler")
 	}
+	pattern := route.Pattern
 
-	// duplicate check
-	r := sm.tree.search(method, pattern)
-	if r.Method == method && r.Pattern == pattern {
-		panic("http: duplicated registrations for " + method + " " + pattern)
+	methods := []string{method}
+	if method == methodAll {
+		methods = methodSlice
 	}
 
-	if method == methodAll {
-		for _, m := range methodSlice {
-			// duplicate check
-			r := sm.tree.search(m, pattern)
-			if r.Method == m && r.Pattern == pattern {
-				panic("http: duplicated registrations for " + m + " " + pattern)
-			}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
-			// insert
-			sm.tree.insert(m, pattern, Route{
-				Method:      m,
-				Pattern:     pattern,
-				HandlerFunc: handler,
-			})
+	// duplicate check before inserting any route
+	tree := sm.loadTree()
+	for _, m := range methods {
+		if tree.exists(m, pattern) {
+			panic("http: duplicated registrations for " + m + " " + pattern)
 		}
-		return
 	}
 
-	sm.tree.insert(method, pattern, Route{
-		Method:      method,
-		Pattern:     pattern,
-		HandlerFunc: handler,
+	// insert into a copy, and publish it
+	route.handler = sm.wrap(route.HandlerFunc)
+	tree = tree.copy()
+	for _, m := range methods {
+		route.Method = m
+		tree.insert(m, pattern, route)
+	}
+	sm.tree.Store(tree)
+	return &RouteRef{mux: sm, methods: methods, pattern: pattern}
+}
+
+// Routes returns every registered route sorted by pattern and method.
+// The routes registered by Mount are not included.
+func (sm *ServeMux) Routes() []Route {
+	routes := slices.DeleteFunc(sm.loadTree().routes(nil), func(route Route) bool {
+		return route.mount
 	})
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	// a pattern with optional params is held by several nodes
+	return slices.CompactFunc(routes, func(a, b Route) bool {
+		return a.Method == b.Method && a.Pattern == b.Pattern
+	})
+}
+
+// Walk calls fn for every registered route in the order of Routes.
+// It stops and returns the error if fn returns an error.
+func (sm *ServeMux) Walk(fn func(Route) error) error {
+	for _, route := range sm.Routes() {
+		if err := fn(route); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove unregisters the route for method and pattern.
+// It returns false if no such route is registered.
+func (sm *ServeMux) Remove(method, pattern string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	tree := sm.loadTree()
+	nodes := tree.nodes(method, pattern)
+	if len(nodes) == 0 {
+		return false
+	}
+	name := nodes[0].Route.Name
+	tree = tree.copy()
+	tree.remove(method, pattern)
+	sm.tree.Store(tree)
+
+	// forget the name when no method of the pattern keeps it
+	if name != "" && !tree.hasName(pattern, name) {
+		delete(sm.names, name)
+	}
+	return true
 }
 
-func (sm *ServeMux) Get(path string, handler http.HandlerFunc) {
-	sm.method(http.MethodGet, path, handler)
+// Replace swaps the handler of the route for method and pattern.
+// handler is wrapped by the middlewares the route is registered with, such as the ones of Group.
+// It returns false if no such route is registered.
+func (sm *ServeMux) Replace(method, pattern string, handler http.HandlerFunc) bool {
+	if handler == nil {
+		panic("http: nil handler")
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	tree := sm.loadTree()
+	if len(tree.nodes(method, pattern)) == 0 {
+		return false
+	}
+	tree = tree.copy()
+	for _, n := range tree.modifiable(method, pattern) {
+		n.Route.HandlerFunc = handler
+		if n.Route.wrap != nil {
+			n.Route.HandlerFunc = n.Route.wrap(handler)
+		}
+		n.Route.handler = sm.wrap(n.Route.HandlerFunc)
+	}
+	sm.tree.Store(tree)
+	return true
 }
 
-func (sm *ServeMux) Post(path string, handler http.HandlerFunc) {
-	sm.method(http.MethodPost, path, handler)
+func (sm *ServeMux) Get(path string, handler http.HandlerFunc) *RouteRef {
+	return sm.handle(http.MethodGet, path, handler)
 }
 
-func (sm *ServeMux) Put(path string, handler http.HandlerFunc) {
-	sm.method(http.MethodPut, path, handler)
+func (sm *ServeMux) Post(path string, handler http.HandlerFunc) *RouteRef {
+	return sm.handle(http.MethodPost, path, handler)
 }
 
-func (sm *ServeMux) Delete(path string, handler http.HandlerFunc) {
-	sm.method(http.MethodDelete, path, handler)
+func (sm *ServeMux) Put(path string, handler http.HandlerFunc) *RouteRef {
+	return sm.handle(http.MethodPut, path, handler)
 }
 
-func (sm *ServeMux) Head(path string, handler http.HandlerFunc) {
-	sm.method(http.MethodHead, path, handler)
+func (sm *ServeMux) Delete(path string, handler http.HandlerFunc) *RouteRef {
+	return sm.handle(http.MethodDelete, path, handler)
 }
 
-func (sm *ServeMux) Options(path string, handler http.HandlerFunc) {
-	sm.method(http.MethodOptions, path, handler)
+func (sm *ServeMux) Head(path string, handler http.HandlerFunc) *RouteRef {
+	return sm.handle(http.MethodHead, path, handler)
 }
 
-func (sm *ServeMux) Patch(path string, handler http.HandlerFunc) {
-	sm.method(http.MethodPatch, path, handler)
+func (sm *ServeMux) Options(path string, handler http.HandlerFunc) *RouteRef {
+	return sm.handle(http.MethodOptions, path, handler)
 }
 
-func (sm *ServeMux) method(method string, path string, handler http.HandlerFunc) {
-	sm.tree.insert(
-		method,
-		path,
-		Route{
-			Method:      method,
-			Pattern:     path,
-			HandlerFunc: handler,
-		},
-	)
+func (sm *ServeMux) Patch(path string, handler http.HandlerFunc) *RouteRef {
+	return sm.handle(http.MethodPatch, path, handler)
 }
 
 type GracefulOpts struct {
@@ -1,11 +1,16 @@
 package minimalmux
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
 )
 
 func Test_handle(t *testing.T) {
@@ -83,6 +88,53 @@ func Test_handle_panic(t *testing.T) {
 		mux.handle(http.MethodGet, "/test", testHandler)
 		mux.handle(methodAll, "/test", testHandler)
 	})
+	t.Run("panic if Get is duplicated", func(t *testing.T) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Errorf("panic not occur")
+			}
+			t.Log(err)
+		}()
+		mux := NewServeMux()
+		mux.Get("/test/:id", testHandler)
+		mux.Get("/test/:id", testHandler)
+	})
+	t.Run("panic if HandleFunc duplicates Post", func(t *testing.T) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Errorf("panic not occur")
+			}
+			t.Log(err)
+		}()
+		mux := NewServeMux()
+		mux.Post("/test", testHandler)
+		mux.HandleFunc("/test", testHandler)
+	})
+	t.Run("panic if param names conflict", func(t *testing.T) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Errorf("panic not occur")
+			}
+			t.Log(err)
+		}()
+		mux := NewServeMux()
+		mux.Get("/users/:id", testHandler)
+		mux.Get("/users/:uid/posts", testHandler)
+	})
+	t.Run("panic if catch-all is not the last segment", func(t *testing.T) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Errorf("panic not occur")
+			}
+			t.Log(err)
+		}()
+		mux := NewServeMux()
+		mux.handle(http.MethodGet, "/static/*path/foo", testHandler)
+	})
 }
 
 func Test_GetPostPubDeleteHeadOptionsPatchmethods(t *testing.T) {
@@ -195,9 +247,9 @@ func TestServeMuxRouting_multipleRoutes(t *testing.T) {
 			req:          http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/test"}},
 			expectStatus: http.StatusOK,
 		},
-		{ // NOTE: should return `method not allowed`?
+		{
 			req:          http.Request{Method: http.MethodPost, URL: &url.URL{Path: "/test"}},
-			expectStatus: http.StatusNotFound,
+			expectStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			req:          http.Request{Method: http.MethodPost, URL: &url.URL{Path: "/test/foo"}},
@@ -205,6 +257,10 @@ func TestServeMuxRouting_multipleRoutes(t *testing.T) {
 		},
 		{
 			req:          http.Request{Method: http.MethodPost, URL: &url.URL{Path: "/test/foo/1"}},
+			expectStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			req:          http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/unknown"}},
 			expectStatus: http.StatusNotFound,
 		},
 		{
@@ -223,9 +279,9 @@ func TestServeMuxRouting_multipleRoutes(t *testing.T) {
 			req:          http.Request{Method: http.MethodPut, URL: &url.URL{Path: "/test/foo/123/bar/456"}},
 			expectStatus: http.StatusOK,
 		},
-		{ // FIXME: tailing slash should be ignored??
+		{ // a param does not match the empty segment after the trailing slash
 			req:          http.Request{Method: http.MethodPut, URL: &url.URL{Path: "/test/foo/123/bar/"}},
-			expectStatus: http.StatusOK,
+			expectStatus: http.StatusNotFound,
 		},
 	}
 
@@ -325,6 +381,40 @@ func TestServeMuxHandler(t *testing.T) {
 	if pattern != "/test" {
 		t.Errorf("pattern not equal. got: %s, want: %s", pattern, "/test")
 	}
+
+	t.Run("agrees with ServeHTTP", func(t *testing.T) {
+		mux := NewServeMux(WithTrailingSlash(TrailingSlashRedirect), WithCaseInsensitive())
+		mux.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("user " + GetParams(r)["id"]))
+		})
+
+		tcs := []struct {
+			method        string
+			path          string
+			expectPattern string
+			expectStatus  int
+			expectBody    string
+		}{
+			{method: http.MethodGet, path: "/users/1", expectPattern: "/users/:id", expectStatus: http.StatusOK, expectBody: "user 1"},
+			{method: http.MethodHead, path: "/users/1", expectPattern: "/users/:id", expectStatus: http.StatusOK},
+			{method: http.MethodGet, path: "/USERS/2", expectPattern: "/users/:id", expectStatus: http.StatusOK, expectBody: "user 2"},
+			{method: http.MethodGet, path: "/users/1/", expectPattern: "", expectStatus: http.StatusMovedPermanently},
+			{method: http.MethodPost, path: "/users/1", expectPattern: "", expectStatus: http.StatusMethodNotAllowed},
+			{method: http.MethodGet, path: "/unknown", expectPattern: "", expectStatus: http.StatusNotFound},
+		}
+		for _, tc := range tcs {
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+			h, pattern := mux.Handler(r)
+			testEqual(t, pattern, tc.expectPattern)
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			testEqual(t, w.Code, tc.expectStatus)
+			if tc.expectBody != "" {
+				testEqual(t, w.Body.String(), tc.expectBody)
+			}
+		}
+	})
 }
 
 // longest match
@@ -369,8 +459,553 @@ func TestRoutingLongestMatch(t *testing.T) {
 	check("/test/some/bar", "c\n")
 	check("/test/some/baz", "d\n")
 	check("/test/fo/baz", "d\n")
-	// check("/test/foo/baz", "d\n") // NOTE: not found
+	check("/test/foo/baz", "d\n")
 	check("/test/fooo/baz", "d\n")
 	check("/a", "e\n")
 	check("/testa", "e\n")
 }
+
+func TestServeMuxCatchAll(t *testing.T) {
+	mux := NewServeMux()
+	mux.Get("/static/*path", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(GetParams(r)["path"]))
+	})
+	ts := httptest.NewServer(mux)
+
+	res := testHttpRequest(t, ts, http.MethodGet, "/static/css/app.css")
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "css/app.css" {
+		t.Errorf("Response body not equal. got: %s, want: %s", string(body), "css/app.css")
+	}
+}
+
+func TestServeMuxMethodNotAllowed(t *testing.T) {
+	mux := NewServeMux()
+	for _, r := range testingRegisteredRoutes {
+		mux.handle(r.method, r.pattern, testHandler)
+	}
+	ts := httptest.NewServer(mux)
+
+	tcs := []struct {
+		method      string
+		path        string
+		expectAllow string
+	}{
+		{method: http.MethodPost, path: "/test", expectAllow: "GET, HEAD, OPTIONS"},
+		{method: http.MethodGet, path: "/test/foo/1/bar", expectAllow: "OPTIONS, POST"},
+		{method: http.MethodPost, path: "/test/foo/1", expectAllow: "DELETE, OPTIONS, PUT"},
+		{method: http.MethodDelete, path: "/test/foo/1/bar/2", expectAllow: "GET, HEAD, OPTIONS, PUT"},
+	}
+	for _, tc := range tcs {
+		res := testHttpRequest(t, ts, tc.method, tc.path)
+		if res.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("Status code not equal. got: %d, want: %d", res.StatusCode, http.StatusMethodNotAllowed)
+		}
+		if allow := res.Header.Get("Allow"); allow != tc.expectAllow {
+			t.Errorf("Allow header not equal. got: %s, want: %s", allow, tc.expectAllow)
+		}
+	}
+}
+
+func TestServeMuxAutomaticOptions(t *testing.T) {
+	mux := NewServeMux()
+	mux.Get("/test", testHandler)
+	mux.Post("/test", testHandler)
+	mux.Put("/custom", testHandler)
+	mux.Options("/custom", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", "PUT")
+		w.WriteHeader(http.StatusOK)
+	})
+	ts := httptest.NewServer(mux)
+
+	tcs := []struct {
+		path         string
+		expectStatus int
+		expectAllow  string
+	}{
+		{path: "/test", expectStatus: http.StatusNoContent, expectAllow: "GET, HEAD, OPTIONS, POST"},
+		{path: "/custom", expectStatus: http.StatusOK, expectAllow: "PUT"},
+		{path: "/unknown", expectStatus: http.StatusNotFound, expectAllow: ""},
+	}
+	for _, tc := range tcs {
+		res := testHttpRequest(t, ts, http.MethodOptions, tc.path)
+		if res.StatusCode != tc.expectStatus {
+			t.Errorf("Status code not equal. got: %d, want: %d", res.StatusCode, tc.expectStatus)
+		}
+		if allow := res.Header.Get("Allow"); allow != tc.expectAllow {
+			t.Errorf("Allow header not equal. got: %s, want: %s", allow, tc.expectAllow)
+		}
+	}
+}
+
+func TestServeMuxMount(t *testing.T) {
+	billing := NewServeMux()
+	billing.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("billing index " + r.URL.Path + "\n"))
+	})
+	billing.Get("/invoices/:id", func(w http.ResponseWriter, r *http.Request) {
+		p := GetParams(r)
+		w.Write([]byte("org " + p["org"] + " invoice " + p["id"] + " " + r.URL.Path + "\n"))
+	})
+
+	mux := NewServeMux()
+	mux.Mount("/orgs/:org/billing", billing)
+	mux.Mount("/static/", http.FileServer(http.FS(fstest.MapFS{
+		"css/app.css": {Data: []byte("body {}\n")},
+	})))
+	mux.Get("/orgs/:org", testHandler)
+
+	ts := httptest.NewServer(mux)
+
+	tcs := []struct {
+		method       string
+		path         string
+		expectStatus int
+		expectBody   string
+	}{
+		{method: http.MethodGet, path: "/orgs/acme/billing", expectStatus: http.StatusOK, expectBody: "billing index /\n"},
+		{method: http.MethodGet, path: "/orgs/acme/billing/", expectStatus: http.StatusOK, expectBody: "billing index /\n"},
+		{method: http.MethodGet, path: "/orgs/acme/billing/invoices/42", expectStatus: http.StatusOK, expectBody: "org acme invoice 42 /invoices/42\n"},
+		{method: http.MethodPost, path: "/orgs/acme/billing/invoices/42", expectStatus: http.StatusMethodNotAllowed, expectBody: "Method Not Allowed\n"},
+		{method: http.MethodGet, path: "/static/css/app.css", expectStatus: http.StatusOK, expectBody: "body {}\n"},
+		{method: http.MethodGet, path: "/orgs/acme", expectStatus: http.StatusOK, expectBody: "test\n"},
+	}
+	for _, tc := range tcs {
+		res := testHttpRequest(t, ts, tc.method, tc.path)
+		if res.StatusCode != tc.expectStatus {
+			t.Errorf("%s %s: Status code not equal. got: %d, want: %d", tc.method, tc.path, res.StatusCode, tc.expectStatus)
+		}
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != tc.expectBody {
+			t.Errorf("%s %s: Response body not equal. got: %q, want: %q", tc.method, tc.path, string(body), tc.expectBody)
+		}
+	}
+}
+
+func TestServeMuxMountIntrospection(t *testing.T) {
+	var pattern string
+	var params, routeParams map[string]string
+	mux := NewServeMux()
+	mux.Use(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern = GetPattern(r)
+			params = GetParams(r)
+			route, _ := GetRoute(r)
+			routeParams = route.PathParamMap
+			h.ServeHTTP(w, r)
+		})
+	})
+	mux.Mount("/orgs/:org/billing", NewServeMux())
+	mux.Get("/orgs/:org", testHandler)
+
+	// mount routes are not listed
+	routes := mux.Routes()
+	testEqual(t, len(routes), 1)
+	testEqual(t, routes[0].Pattern, "/orgs/:org")
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orgs/acme/billing/invoices/42", nil))
+	testEqual(t, pattern, "/orgs/:org/billing/*")
+	// the path below the prefix is not a path param
+	testEqual(t, len(params), 1)
+	testEqual(t, params["org"], "acme")
+	testEqual(t, len(routeParams), 1)
+	testEqual(t, routeParams["org"], "acme")
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orgs/acme/billing", nil))
+	testEqual(t, pattern, "/orgs/:org/billing")
+}
+
+func TestGetRoute(t *testing.T) {
+	var got Route
+	var ok bool
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		got, ok = GetRoute(r)
+	}
+
+	mux := NewServeMux()
+	mux.Put("/users/:id/posts/:postID", handler)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/users/1/posts/2", nil)
+	mux.ServeHTTP(w, r)
+
+	if !ok {
+		t.Fatal("route not found in request")
+	}
+	testEqual(t, got.Method, http.MethodPut)
+	testEqual(t, got.Pattern, "/users/:id/posts/:postID")
+	testEqual(t, got.PathParamMap["id"], "1")
+	testEqual(t, got.PathParamMap["postID"], "2")
+
+	r = httptest.NewRequest(http.MethodGet, "/unknown", nil)
+	if _, ok := GetRoute(r); ok {
+		t.Error("route must not be found")
+	}
+}
+
+func TestServeMuxConcurrentRegistration(t *testing.T) {
+	mux := NewServeMux()
+	mux.Get("/users/:id", testHandler)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				mux.Get(fmt.Sprintf("/items/%d/%d", i, j), testHandler)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+				if w.Code != http.StatusOK {
+					t.Errorf("Status code not equal. got: %d, want: %d", w.Code, http.StatusOK)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items/7/49", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Status code not equal. got: %d, want: %d", w.Code, http.StatusOK)
+	}
+}
+
+// discardResponseWriter is a http.ResponseWriter for benchmarks
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+func (w *discardResponseWriter) WriteHeader(int) {}
+
+func BenchmarkServeMuxParallel(b *testing.B) {
+	mux := NewServeMux()
+	for _, pattern := range benchmarkRoutes() {
+		mux.Get(pattern, func(w http.ResponseWriter, r *http.Request) {})
+	}
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/resource99/stats", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		w := &discardResponseWriter{}
+		for pb.Next() {
+			mux.ServeHTTP(w, r)
+		}
+	})
+}
+
+func BenchmarkServeMuxParallelMiddlewares(b *testing.B) {
+	mux := NewServeMux()
+	for i := 0; i < 3; i++ {
+		mux.Use(func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				h.ServeHTTP(w, r)
+			})
+		})
+	}
+	for _, pattern := range benchmarkRoutes() {
+		mux.Get(pattern, func(w http.ResponseWriter, r *http.Request) {})
+	}
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/resource99/stats", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		w := &discardResponseWriter{}
+		for pb.Next() {
+			mux.ServeHTTP(w, r)
+		}
+	})
+}
+
+// BenchmarkTreeSearchParallel compares the lookup through an atomic pointer
+// with the lookup under a read lock, which the mux used before.
+func BenchmarkTreeSearchParallel(b *testing.B) {
+	tree := benchmarkTree()
+
+	b.Run("atomic", func(b *testing.B) {
+		var p atomic.Pointer[Node]
+		p.Store(tree)
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				p.Load().search(http.MethodGet, "/api/v1/resource99/stats")
+			}
+		})
+	})
+
+	b.Run("rwmutex", func(b *testing.B) {
+		var mu sync.RWMutex
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				mu.RLock()
+				tree.search(http.MethodGet, "/api/v1/resource99/stats")
+				mu.RUnlock()
+			}
+		})
+	})
+}
+
+func TestServeMuxRemoveReplace(t *testing.T) {
+	handler := func(s string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(s))
+		}
+	}
+	mux := NewServeMux()
+	mux.Get("/plugins/:name", handler("v1"))
+	mux.Post("/plugins/:name", handler("post"))
+
+	check := func(method, path string, expectStatus int, expectBody string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		if w.Code != expectStatus {
+			t.Errorf("Status code not equal. got: %d, want: %d", w.Code, expectStatus)
+		}
+		if expectBody != "" && w.Body.String() != expectBody {
+			t.Errorf("Response body not equal. got: %s, want: %s", w.Body.String(), expectBody)
+		}
+	}
+
+	check(http.MethodGet, "/plugins/a", http.StatusOK, "v1")
+
+	testEqual(t, mux.Replace(http.MethodGet, "/plugins/:name", handler("v2")), true)
+	testEqual(t, mux.Replace(http.MethodPut, "/plugins/:name", handler("v2")), false)
+	check(http.MethodGet, "/plugins/a", http.StatusOK, "v2")
+
+	testEqual(t, mux.Remove(http.MethodGet, "/plugins/:name"), true)
+	testEqual(t, mux.Remove(http.MethodGet, "/plugins/:name"), false)
+	check(http.MethodGet, "/plugins/a", http.StatusMethodNotAllowed, "")
+
+	testEqual(t, mux.Remove(http.MethodPost, "/plugins/:name"), true)
+	check(http.MethodPost, "/plugins/a", http.StatusNotFound, "")
+
+	// can be registered again
+	mux.Get("/plugins/:name", handler("v3"))
+	check(http.MethodGet, "/plugins/a", http.StatusOK, "v3")
+}
+
+func TestServeMuxRemoveConcurrent(t *testing.T) {
+	mux := NewServeMux()
+	mux.Get("/stable/:id", testHandler)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			mux.Get("/flag/:id", testHandler)
+			mux.Replace(http.MethodGet, "/flag/:id", testHandler)
+			mux.Remove(http.MethodGet, "/flag/:id")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stable/1", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("Status code not equal. got: %d, want: %d", w.Code, http.StatusOK)
+				return
+			}
+			w = httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flag/1", nil))
+		}
+	}()
+	wg.Wait()
+}
+
+func TestServeMuxRoutes(t *testing.T) {
+	mux := NewServeMux()
+	mux.Post("/users", testHandler)
+	mux.Get("/users/:id", testHandler).Name("user.show")
+	mux.Get("/users", testHandler)
+	mux.Group("/admin", func(g *Group) {
+		g.Use(testMiddleware("a"), testMiddleware("b"))
+		g.Delete("/users/:id", testHandler)
+	})
+
+	expected := []Route{
+		{Method: http.MethodDelete, Pattern: "/admin/users/:id", MiddlewareCount: 2},
+		{Method: http.MethodGet, Pattern: "/users"},
+		{Method: http.MethodPost, Pattern: "/users"},
+		{Method: http.MethodGet, Pattern: "/users/:id", Name: "user.show"},
+	}
+	routes := mux.Routes()
+	if len(routes) != len(expected) {
+		t.Fatalf("routes length not equal. got: %d, want: %d", len(routes), len(expected))
+	}
+	for i, r := range routes {
+		testEqual(t, r.Method, expected[i].Method)
+		testEqual(t, r.Pattern, expected[i].Pattern)
+		testEqual(t, r.Name, expected[i].Name)
+		testEqual(t, r.MiddlewareCount, expected[i].MiddlewareCount)
+		if r.IsBlank() {
+			t.Errorf("handler of %s %s is nil", r.Method, r.Pattern)
+		}
+	}
+}
+
+func TestServeMuxWalk(t *testing.T) {
+	mux := NewServeMux()
+	mux.Get("/a", testHandler)
+	mux.Get("/b", testHandler)
+	mux.Get("/c", testHandler)
+
+	var got []string
+	errStop := errors.New("stop")
+	err := mux.Walk(func(r Route) error {
+		got = append(got, r.Pattern)
+		if r.Pattern == "/b" {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("error not equal. got: %v, want: %v", err, errStop)
+	}
+	if len(got) != 2 || got[0] != "/a" || got[1] != "/b" {
+		t.Errorf("walked routes not equal. got: %v", got)
+	}
+}
+
+func TestServeMuxOptionalParams(t *testing.T) {
+	mux := NewServeMux()
+	mux.Get("/posts/{page?:int}", func(w http.ResponseWriter, r *http.Request) {
+		page, ok := GetParams(r)["page"]
+		w.Write([]byte(fmt.Sprintf("%s %v %s", page, ok, GetPattern(r))))
+	})
+
+	tcs := []struct {
+		path         string
+		expectStatus int
+		expectBody   string
+	}{
+		{path: "/posts", expectStatus: http.StatusOK, expectBody: " false /posts/{page?:int}"},
+		{path: "/posts/2", expectStatus: http.StatusOK, expectBody: "2 true /posts/{page?:int}"},
+		{path: "/posts/abc", expectStatus: http.StatusNotFound},
+		{path: "/posts/", expectStatus: http.StatusNotFound},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != tc.expectStatus {
+			t.Errorf("%s: Status code not equal. got: %d, want: %d", tc.path, w.Code, tc.expectStatus)
+		}
+		if tc.expectBody != "" && w.Body.String() != tc.expectBody {
+			t.Errorf("%s: Response body not equal. got: %q, want: %q", tc.path, w.Body.String(), tc.expectBody)
+		}
+	}
+
+	routes := mux.Routes()
+	if len(routes) != 1 || routes[0].Pattern != "/posts/{page?:int}" {
+		t.Errorf("routes not equal. got: %v", routes)
+	}
+
+	// duplicates one of the expanded patterns
+	func() {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("panic not occur")
+			}
+		}()
+		mux.Get("/posts", testHandler)
+	}()
+
+	// an expanded pattern does not stand for the route
+	testEqual(t, mux.Replace(http.MethodGet, "/posts", testHandler), false)
+	testEqual(t, mux.Remove(http.MethodGet, "/posts"), false)
+	testEqual(t, mux.Remove(http.MethodGet, "/posts/{page}"), false)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts/2", nil))
+	testEqual(t, w.Body.String(), "2 true /posts/{page?:int}")
+
+	if !mux.Remove(http.MethodGet, "/posts/{page?:int}") {
+		t.Fatal("route not removed")
+	}
+	for _, path := range []string{"/posts", "/posts/2"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: Status code not equal. got: %d, want: %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+	testEqual(t, len(mux.Routes()), 0)
+}
+
+func TestServeMuxCaseInsensitive(t *testing.T) {
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(GetParams(r)["id"]))
+	}
+
+	tcs := []struct {
+		opts           []Option
+		method         string
+		path           string
+		expectStatus   int
+		expectLocation string
+		expectBody     string
+	}{
+		{method: http.MethodGet, path: "/API/Users/Abc", expectStatus: http.StatusNotFound},
+		{opts: []Option{WithCaseInsensitive()}, method: http.MethodGet, path: "/api/users/Abc", expectStatus: http.StatusOK, expectBody: "Abc"},
+		{opts: []Option{WithCaseInsensitive()}, method: http.MethodGet, path: "/API/Users/Abc", expectStatus: http.StatusOK, expectBody: "Abc"},
+		{opts: []Option{WithCaseInsensitive()}, method: http.MethodDelete, path: "/API/Users/Abc", expectStatus: http.StatusMethodNotAllowed},
+		{opts: []Option{WithCaseRedirect()}, method: http.MethodGet, path: "/api/users/Abc", expectStatus: http.StatusOK, expectBody: "Abc"},
+		{opts: []Option{WithCaseRedirect()}, method: http.MethodGet, path: "/API/Users/Abc?x=1", expectStatus: http.StatusMovedPermanently, expectLocation: "/api/users/Abc?x=1"},
+		{opts: []Option{WithCaseRedirect()}, method: http.MethodPut, path: "/API/Users/Abc", expectStatus: http.StatusPermanentRedirect, expectLocation: "/api/users/Abc"},
+		{opts: []Option{WithCaseRedirect(), WithTrailingSlash(TrailingSlashRedirect)}, method: http.MethodGet, path: "/API/Users/Abc/", expectStatus: http.StatusMovedPermanently, expectLocation: "/api/users/Abc"},
+	}
+	for _, tc := range tcs {
+		mux := NewServeMux(tc.opts...)
+		mux.Get("/api/users/{id}", echo)
+		mux.Put("/api/users/{id}", echo)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.expectStatus {
+			t.Errorf("%s %s: Status code not equal. got: %d, want: %d", tc.method, tc.path, w.Code, tc.expectStatus)
+		}
+		if location := w.Header().Get("Location"); location != tc.expectLocation {
+			t.Errorf("%s %s: Location not equal. got: %q, want: %q", tc.method, tc.path, location, tc.expectLocation)
+		}
+		if tc.expectBody != "" && w.Body.String() != tc.expectBody {
+			t.Errorf("%s %s: Response body not equal. got: %q, want: %q", tc.method, tc.path, w.Body.String(), tc.expectBody)
+		}
+	}
+}
+
+func BenchmarkServeMuxRegister(b *testing.B) {
+	routes := benchmarkRoutes()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mux := NewServeMux()
+		for _, pattern := range routes {
+			mux.HandleFunc(pattern, testHandler)
+		}
+	}
+}
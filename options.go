@@ -0,0 +1,77 @@
+package minimalmux
+
+import "net/http"
+
+// Option configures a ServeMux created by NewServeMux.
+type Option func(*ServeMux)
+
+// WithNotFoundHandler replaces the handler called when no route matches the request path.
+func WithNotFoundHandler(h http.Handler) Option {
+	return func(sm *ServeMux) {
+		sm.notFoundHandler = h.ServeHTTP
+	}
+}
+
+// WithMethodNotAllowedHandler replaces the handler called when the request path
+// matches a route registered for another method.
+// The Allow header is already set when the handler is called.
+func WithMethodNotAllowedHandler(h http.Handler) Option {
+	return func(sm *ServeMux) {
+		sm.methodNotAllowedHandler = h.ServeHTTP
+	}
+}
+
+// WithTrailingSlash sets the policy for a request path which differs from
+// a registered pattern only in its trailing slash. The default is TrailingSlashStrict.
+func WithTrailingSlash(policy TrailingSlash) Option {
+	return func(sm *ServeMux) {
+		sm.trailingSlash = policy
+	}
+}
+
+// WithCleanPath makes the mux route on the canonical form of the request path,
+// which has no //, /./ or /../ element.
+// GET and HEAD requests for a non-canonical path are redirected to the canonical one.
+func WithCleanPath() Option {
+	return func(sm *ServeMux) {
+		sm.cleanPath = true
+	}
+}
+
+// WithRawPath makes the mux route on the escaped request path, r.URL.RawPath if set,
+// so that an encoded slash (%2F) in a path param does not split the segment.
+// The values of path params are unescaped after routing.
+// Static parts of patterns are matched against the escaped path.
+func WithRawPath() Option {
+	return func(sm *ServeMux) {
+		sm.rawPath = true
+	}
+}
+
+// WithCaseInsensitive makes static parts of patterns match the request path
+// ignoring ASCII case, such as /API/Users for /api/users.
+// Path params keep the case of the request.
+// When a static part is registered in several casings, the one with the most
+// bytes in the same case as the request is preferred, regardless of the registration order.
+func WithCaseInsensitive() Option {
+	return func(sm *ServeMux) {
+		sm.caseInsensitive = true
+	}
+}
+
+// WithCaseRedirect is WithCaseInsensitive which also redirects the request
+// to the path in the registered case, with 301 for GET and HEAD, and 308 for the other methods.
+func WithCaseRedirect() Option {
+	return func(sm *ServeMux) {
+		sm.caseInsensitive = true
+		sm.caseRedirect = true
+	}
+}
+
+// WithMiddlewares sets the middlewares which wrap every handler the mux dispatches to,
+// including the not found and method not allowed handlers.
+func WithMiddlewares(ms *Middlewares) Option {
+	return func(sm *ServeMux) {
+		sm.Use(*ms...)
+	}
+}
@@ -0,0 +1,74 @@
+package minimalmux
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithNotFoundHandler(t *testing.T) {
+	mux := NewServeMux(WithNotFoundHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	})))
+	mux.Get("/test", testHandler)
+	ts := httptest.NewServer(mux)
+
+	res := testHttpRequest(t, ts, http.MethodGet, "/unknown")
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("Status code not equal. got: %d, want: %d", res.StatusCode, http.StatusNotFound)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type not equal. got: %s, want: %s", ct, "application/json")
+	}
+}
+
+func TestWithMethodNotAllowedHandler(t *testing.T) {
+	mux := NewServeMux(WithMethodNotAllowedHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("allow: " + w.Header().Get("Allow")))
+	})))
+	mux.Get("/test", testHandler)
+	ts := httptest.NewServer(mux)
+
+	res := testHttpRequest(t, ts, http.MethodPost, "/test")
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Status code not equal. got: %d, want: %d", res.StatusCode, http.StatusMethodNotAllowed)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "allow: GET, HEAD, OPTIONS" {
+		t.Errorf("Response body not equal. got: %s, want: %s", string(body), "allow: GET, HEAD, OPTIONS")
+	}
+}
+
+func TestWithMiddlewares(t *testing.T) {
+	ms := NewMiddlewares().Append(testMiddleware("a"))
+	mux := NewServeMux(
+		WithMiddlewares(ms),
+		WithNotFoundHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("not found\n"))
+		})),
+	)
+	mux.Get("/test", testHandler)
+	ts := httptest.NewServer(mux)
+
+	check := func(method, path, expect string) {
+		res := testHttpRequest(t, ts, method, path)
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != expect {
+			t.Errorf("Response body not equal. got: %q, want: %q", string(body), expect)
+		}
+	}
+
+	check(http.MethodGet, "/test", "a\ntest\n")
+	check(http.MethodGet, "/unknown", "a\nnot found\n")
+	check(http.MethodPost, "/test", "a\nMethod Not Allowed\n")
+}
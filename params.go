@@ -0,0 +1,107 @@
+package minimalmux
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// paramTypes are the typed markers of path params, such as {id:int}.
+// a param value which is not valid for the type does not match the route.
+var paramTypes = map[string]func(string) bool{
+	"int": func(s string) bool {
+		_, err := strconv.Atoi(s)
+		return err == nil
+	},
+	"int64": func(s string) bool {
+		_, err := strconv.ParseInt(s, 10, 64)
+		return err == nil
+	},
+	"uuid": isUUID,
+	"bool": func(s string) bool {
+		_, err := strconv.ParseBool(s)
+		return err == nil
+	},
+}
+
+// isUUID returns true if s is a UUID in the form of xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			c := s[i]
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// param returns the path param of key, or an error if r has no such param.
+func param(r *http.Request, key string) (string, error) {
+	v, ok := GetParams(r)[key]
+	if !ok {
+		return "", fmt.Errorf("http: path param %s not found", key)
+	}
+	return v, nil
+}
+
+// ParamInt returns the path param of key as int.
+func ParamInt(r *http.Request, key string) (int, error) {
+	v, err := param(r, key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("http: path param %s: %w", key, err)
+	}
+	return i, nil
+}
+
+// ParamInt64 returns the path param of key as int64.
+func ParamInt64(r *http.Request, key string) (int64, error) {
+	v, err := param(r, key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("http: path param %s: %w", key, err)
+	}
+	return i, nil
+}
+
+// ParamUUID returns the path param of key after validating it as a UUID.
+func ParamUUID(r *http.Request, key string) (string, error) {
+	v, err := param(r, key)
+	if err != nil {
+		return "", err
+	}
+	if !isUUID(v) {
+		return "", fmt.Errorf("http: path param %s: invalid UUID %q", key, v)
+	}
+	return v, nil
+}
+
+// ParamBool returns the path param of key as bool.
+// It accepts the values which strconv.ParseBool accepts.
+func ParamBool(r *http.Request, key string) (bool, error) {
+	v, err := param(r, key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("http: path param %s: %w", key, err)
+	}
+	return b, nil
+}
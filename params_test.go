@@ -0,0 +1,94 @@
+package minimalmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTypedParamRouting(t *testing.T) {
+	mux := NewServeMux()
+	mux.Get("/users/{id:int}", testHandler)
+	mux.Get("/orders/{id:int64}", testHandler)
+	mux.Get("/items/{id:uuid}", testHandler)
+	mux.Get("/flags/{on:bool}", testHandler)
+	mux.Get("/users/{id:uuid}/profile", testHandler)
+
+	tcs := []struct {
+		path         string
+		expectStatus int
+	}{
+		{path: "/users/42", expectStatus: http.StatusOK},
+		{path: "/users/-1", expectStatus: http.StatusOK},
+		{path: "/users/abc", expectStatus: http.StatusNotFound},
+		{path: "/orders/9223372036854775807", expectStatus: http.StatusOK},
+		{path: "/orders/9223372036854775808", expectStatus: http.StatusNotFound},
+		{path: "/items/123e4567-e89b-12d3-a456-426614174000", expectStatus: http.StatusOK},
+		{path: "/items/123e4567-e89b-12d3-a456-42661417400", expectStatus: http.StatusNotFound},
+		{path: "/items/123e4567xe89b-12d3-a456-426614174000", expectStatus: http.StatusNotFound},
+		{path: "/flags/true", expectStatus: http.StatusOK},
+		{path: "/flags/yes", expectStatus: http.StatusNotFound},
+		{path: "/users/123e4567-e89b-12d3-a456-426614174000/profile", expectStatus: http.StatusOK},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != tc.expectStatus {
+			t.Errorf("%s: Status code not equal. got: %d, want: %d", tc.path, w.Code, tc.expectStatus)
+		}
+	}
+}
+
+func TestParamAccessors(t *testing.T) {
+	var called bool
+	mux := NewServeMux()
+	mux.Get("/:i/:i64/:uuid/:b/:s", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+
+		i, err := ParamInt(r, "i")
+		if err != nil {
+			t.Error(err)
+		}
+		testEqual(t, i, 42)
+
+		i64, err := ParamInt64(r, "i64")
+		if err != nil {
+			t.Error(err)
+		}
+		testEqual(t, i64, int64(9223372036854775807))
+
+		uuid, err := ParamUUID(r, "uuid")
+		if err != nil {
+			t.Error(err)
+		}
+		testEqual(t, uuid, "123e4567-e89b-12d3-a456-426614174000")
+
+		b, err := ParamBool(r, "b")
+		if err != nil {
+			t.Error(err)
+		}
+		testEqual(t, b, true)
+
+		if _, err := ParamInt(r, "s"); err == nil {
+			t.Error("error not occur for invalid int")
+		}
+		if _, err := ParamInt64(r, "s"); err == nil {
+			t.Error("error not occur for invalid int64")
+		}
+		if _, err := ParamUUID(r, "s"); err == nil {
+			t.Error("error not occur for invalid UUID")
+		}
+		if _, err := ParamBool(r, "s"); err == nil {
+			t.Error("error not occur for invalid bool")
+		}
+		if _, err := ParamInt(r, "missing"); err == nil {
+			t.Error("error not occur for missing param")
+		}
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/42/9223372036854775807/123e4567-e89b-12d3-a456-426614174000/true/abc", nil))
+	if !called {
+		t.Fatal("handler not called")
+	}
+}
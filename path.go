@@ -0,0 +1,44 @@
+package minimalmux
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// cleanPath returns the canonical form of p, eliminating . and .. elements
+// and repeated slashes. the trailing slash of p is kept.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
+
+// unescapeParams unescapes the values of params matched on an escaped path.
+// a value which is not a valid escape is kept as is.
+func unescapeParams(params map[string]string) {
+	for k, v := range params {
+		if unescaped, err := url.PathUnescape(v); err == nil {
+			params[k] = unescaped
+		}
+	}
+}
+
+// escapedSuffix returns the suffix of escaped which is unescaped to path,
+// such as /a%2Fb of /files/a%2Fb for /a/b. it returns "" if there is no such suffix.
+func escapedSuffix(escaped, path string) string {
+	for i := strings.LastIndexByte(escaped, '/'); i >= 0; i = strings.LastIndexByte(escaped[:i], '/') {
+		if unescaped, err := url.PathUnescape(escaped[i:]); err == nil && unescaped == path {
+			return escaped[i:]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package minimalmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCleanPath(t *testing.T) {
+	tcs := []struct {
+		path     string
+		expected string
+	}{
+		{path: "", expected: "/"},
+		{path: "/", expected: "/"},
+		{path: "users", expected: "/users"},
+		{path: "/users//1", expected: "/users/1"},
+		{path: "/users/./1", expected: "/users/1"},
+		{path: "/users/../admin", expected: "/admin"},
+		{path: "/../../etc", expected: "/etc"},
+		{path: "/users//", expected: "/users/"},
+		{path: "/files/a%2Fb/../c", expected: "/files/c"},
+	}
+	for _, tc := range tcs {
+		if got := cleanPath(tc.path); got != tc.expected {
+			t.Errorf("%q: got %q, want %q", tc.path, got, tc.expected)
+		}
+	}
+}
+
+func TestServeMuxCleanPath(t *testing.T) {
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(GetParams(r)["id"]))
+	}
+
+	tcs := []struct {
+		opts           []Option
+		method         string
+		path           string
+		expectStatus   int
+		expectLocation string
+		expectBody     string
+	}{
+		{method: http.MethodGet, path: "/users//1", expectStatus: http.StatusNotFound},
+		{opts: []Option{WithCleanPath()}, method: http.MethodGet, path: "/users/1", expectStatus: http.StatusOK, expectBody: "1"},
+		{opts: []Option{WithCleanPath()}, method: http.MethodGet, path: "/users//1", expectStatus: http.StatusMovedPermanently, expectLocation: "/users/1"},
+		{opts: []Option{WithCleanPath()}, method: http.MethodHead, path: "/users/./1", expectStatus: http.StatusMovedPermanently, expectLocation: "/users/1"},
+		{opts: []Option{WithCleanPath()}, method: http.MethodGet, path: "/admin/../users/1?tab=posts", expectStatus: http.StatusMovedPermanently, expectLocation: "/users/1?tab=posts"},
+		{opts: []Option{WithCleanPath()}, method: http.MethodPut, path: "/users//1", expectStatus: http.StatusOK, expectBody: "1"},
+		{opts: []Option{WithCleanPath()}, method: http.MethodDelete, path: "/users//1", expectStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tc := range tcs {
+		mux := NewServeMux(tc.opts...)
+		mux.Get("/users/{id}", echo)
+		mux.Put("/users/{id}", echo)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.expectStatus {
+			t.Errorf("%s %s: Status code not equal. got: %d, want: %d", tc.method, tc.path, w.Code, tc.expectStatus)
+		}
+		if location := w.Header().Get("Location"); location != tc.expectLocation {
+			t.Errorf("%s %s: Location not equal. got: %q, want: %q", tc.method, tc.path, location, tc.expectLocation)
+		}
+		if tc.expectBody != "" && w.Body.String() != tc.expectBody {
+			t.Errorf("%s %s: Response body not equal. got: %q, want: %q", tc.method, tc.path, w.Body.String(), tc.expectBody)
+		}
+	}
+}
+
+func TestServeMuxRawPath(t *testing.T) {
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(GetParams(r)["name"]))
+	}
+
+	tcs := []struct {
+		opts           []Option
+		path           string
+		expectStatus   int
+		expectLocation string
+		expectBody     string
+	}{
+		{path: "/files/a%2Fb", expectStatus: http.StatusNotFound},
+		{path: "/files/a%20b", expectStatus: http.StatusOK, expectBody: "a b"},
+		{opts: []Option{WithRawPath()}, path: "/files/a%2Fb", expectStatus: http.StatusOK, expectBody: "a/b"},
+		{opts: []Option{WithRawPath()}, path: "/files/a%20b", expectStatus: http.StatusOK, expectBody: "a b"},
+		{opts: []Option{WithRawPath()}, path: "/files/a/b", expectStatus: http.StatusNotFound},
+		{opts: []Option{WithRawPath(), WithCleanPath()}, path: "/files//a%2Fb", expectStatus: http.StatusMovedPermanently, expectLocation: "/files/a%2Fb"},
+	}
+	for _, tc := range tcs {
+		mux := NewServeMux(tc.opts...)
+		mux.Get("/files/{name}", echo)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != tc.expectStatus {
+			t.Errorf("%s: Status code not equal. got: %d, want: %d", tc.path, w.Code, tc.expectStatus)
+		}
+		if location := w.Header().Get("Location"); location != tc.expectLocation {
+			t.Errorf("%s: Location not equal. got: %q, want: %q", tc.path, location, tc.expectLocation)
+		}
+		if tc.expectBody != "" && w.Body.String() != tc.expectBody {
+			t.Errorf("%s: Response body not equal. got: %q, want: %q", tc.path, w.Body.String(), tc.expectBody)
+		}
+	}
+
+	// the escaped path reaches a mounted mux
+	child := NewServeMux(WithRawPath())
+	child.Get("/files/{name}", echo)
+	mux := NewServeMux()
+	mux.Mount("/c", child)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/c/files/a%2Fb", nil))
+	testEqual(t, w.Code, http.StatusOK)
+	testEqual(t, w.Body.String(), "a/b")
+}
+
+func TestEscapedSuffix(t *testing.T) {
+	tcs := []struct {
+		escaped  string
+		path     string
+		expected string
+	}{
+		{escaped: "/c/files/a%2Fb", path: "/files/a/b", expected: "/files/a%2Fb"},
+		{escaped: "/c%2Fd/files/a", path: "/files/a", expected: "/files/a"},
+		{escaped: "/c/a%20b", path: "/a b", expected: "/a%20b"},
+		{escaped: "/c", path: "/", expected: ""},
+		{escaped: "/c/", path: "/", expected: "/"},
+		{escaped: "/c/a", path: "/b", expected: ""},
+	}
+	for _, tc := range tcs {
+		if got := escapedSuffix(tc.escaped, tc.path); got != tc.expected {
+			t.Errorf("%q %q: got %q, want %q", tc.escaped, tc.path, got, tc.expected)
+		}
+	}
+}
@@ -6,61 +6,702 @@
 package minimalmux
 
 import (
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 )
 
+// Node is a node of the routing tree.
+// The root node has one child per method, and each method node holds a
+// prefix-compressed radix tree of the registered patterns.
 type Node struct {
 	Part     string
 	Children []*Node
 	IsWild   bool
 	Key      string
 	Route    Route
+
+	// indices holds the first byte of every static child, in the same order
+	// as the static children at the head of Children. wild children are
+	// placed after them in match priority order: see wildRank.
+	indices string
+
+	// constraint is compiled from the param part such as {id:[0-9]+} or {id:int}
+	constraint func(string) bool
+
+	// suffixed is true if the param is followed by a static part in the same segment,
+	// such as {name} in {name}.{ext}. it is a position apart from the param spanning the segment.
+	suffixed bool
 }
 
 func (n *Node) insert(method, parttern string, route Route) {
-	parts := strings.Split("/"+method+parttern, "/")[1:]
+	for _, p := range expandOptional(parttern) {
+		n.walk(method, p, true).Route = route
+	}
+}
+
+// exists returns true if a route for method is registered at any path of pattern,
+// even if the route is registered with another pattern such as /posts/{page?} for /posts.
+func (n *Node) exists(method, pattern string) bool {
+	for _, p := range expandOptional(pattern) {
+		if node := n.walk(method, p, false); node != nil && !node.Route.IsBlank() {
+			return true
+		}
+	}
+	return false
+}
 
-	for _, part := range parts {
-		child := n.matchChild(part)
-		if child == nil {
-			child = &Node{
-				Part:   part,
-				IsWild: isWild(part),
-				Key:    wildKey(part),
+// nodes returns the nodes which hold the route for method and pattern.
+// a pattern with optional params is held by a node per expanded pattern.
+// the nodes holding a route registered with another pattern are not included.
+func (n *Node) nodes(method, pattern string) []*Node {
+	var nodes []*Node
+	for _, p := range expandOptional(pattern) {
+		if node := n.walk(method, p, false); node != nil && node.Route.Pattern == pattern {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// modifiable returns the nodes which hold the route for method and pattern, like nodes.
+// n must be a copy, and the nodes on the way are copied so that they can be
+// modified without affecting the trees sharing them.
+func (n *Node) modifiable(method, pattern string) []*Node {
+	var nodes []*Node
+	for _, p := range expandOptional(pattern) {
+		if node := n.walk(method, p, false); node != nil && node.Route.Pattern == pattern {
+			nodes = append(nodes, n.walk(method, p, true))
+		}
+	}
+	return nodes
+}
+
+// walk returns the node which holds the route for method and pattern.
+// if create is true, missing nodes are created on the way, and the existing
+// nodes on the way are copied, so that a tree sharing them with n is not modified.
+// n itself must be a copy then. otherwise walk returns nil when the node does not exist.
+func (n *Node) walk(method, pattern string, create bool) *Node {
+	return n.walkFunc(method, pattern, create, nil)
+}
+
+// walkFunc is walk which calls visit, if not nil, with every node on the way
+// from the method child down to the returned node.
+func (n *Node) walkFunc(method, pattern string, create bool, visit func(*Node)) *Node {
+	n = n.methodChild(method, create)
+	if n != nil && visit != nil {
+		visit(n)
+	}
+
+	for i := 0; n != nil && i < len(pattern); {
+		start, end := nextWild(pattern, i)
+		if start == i {
+			part := pattern[start:end]
+			if isCatchAll(part) && end != len(pattern) {
+				panic("http: catch-all must be the last segment in pattern " + pattern)
 			}
-			n.Children = append(n.Children, child)
+			if n.IsWild {
+				panic("http: wildcard " + part + " in pattern " + pattern + " must be separated from the previous wildcard")
+			}
+			suffixed := end < len(pattern) && pattern[end] != '/'
+			for _, child := range n.wildChildren() {
+				// siblings with other constraints are tried in turn, so they may differ in name
+				if child.isCatchAll() == isCatchAll(part) && wildConstraint(child.Part) == wildConstraint(part) &&
+					child.suffixed == suffixed && child.Key != wildKey(part) {
+					if create {
+						panic("http: wildcard " + part + " in pattern " + pattern + " conflicts with existing wildcard " + child.Part)
+					}
+					return nil
+				}
+			}
+			child := n.wildChild(part, suffixed)
+			if child != nil && create {
+				child = n.own(child)
+			}
+			if child == nil && create {
+				child = &Node{
+					Part:       part,
+					IsWild:     true,
+					Key:        wildKey(part),
+					constraint: compileConstraint(part, pattern),
+					suffixed:   suffixed,
+				}
+				n.addWild(child)
+			}
+			n = child
+			if n != nil && visit != nil {
+				visit(n)
+			}
+			i = end
+			continue
 		}
-		n = child
+		if start < 0 {
+			start = len(pattern)
+		}
+		n = n.walkStatic(pattern[i:start], create, visit)
+		i = start
+	}
+	return n
+}
+
+// copy returns a shallow copy of n, which has its own Children slice.
+func (n *Node) copy() *Node {
+	c := *n
+	c.Children = slices.Clone(n.Children)
+	return &c
+}
+
+// own replaces child of n with its copy, and returns the copy.
+func (n *Node) own(child *Node) *Node {
+	i := slices.Index(n.Children, child)
+	c := child.copy()
+	n.Children[i] = c
+	return c
+}
+
+// clone returns a deep copy of n.
+func (n *Node) clone() *Node {
+	c := *n
+	if n.Children != nil {
+		c.Children = make([]*Node, len(n.Children))
+		for i, child := range n.Children {
+			c.Children[i] = child.clone()
+		}
+	}
+	return &c
+}
+
+// remove clears the route for method and pattern, and prunes the branches
+// left without route. It returns false if no such route exists.
+// only the nodes on the way are copied, so n must be a copy.
+func (n *Node) remove(method, pattern string) bool {
+	if len(n.nodes(method, pattern)) == 0 {
+		return false
 	}
-	n.Route = route
+	for _, p := range expandOptional(pattern) {
+		if node := n.walk(method, p, false); node == nil || node.Route.Pattern != pattern {
+			continue
+		}
+		var trail []*Node
+		n.walkFunc(method, p, true, func(node *Node) { trail = append(trail, node) }).Route = Route{}
+
+		// prune from the bottom, as far as the method child
+		for i := len(trail) - 1; i > 0; i-- {
+			trail[i-1].prune(trail[i])
+		}
+		if len(trail[0].Children) == 0 {
+			n.Children = slices.DeleteFunc(n.Children, func(child *Node) bool { return child == trail[0] })
+		}
+	}
+	return true
 }
 
-func (n *Node) matchChild(part string) *Node {
+// prune removes child if it has neither route nor children,
+// and merges child into its only static child if child is static without route.
+// the merged node is copied, so that a tree sharing it is not modified.
+func (n *Node) prune(child *Node) {
+	i := slices.Index(n.Children, child)
+	switch {
+	case child.Route.IsBlank() && len(child.Children) == 0:
+		n.Children = slices.Delete(n.Children, i, i+1)
+		if i < len(n.indices) {
+			n.indices = n.indices[:i] + n.indices[i+1:]
+		}
+	case !child.IsWild && child.Route.IsBlank() && len(child.Children) == 1 && !child.Children[0].IsWild:
+		merged := child.Children[0].copy()
+		merged.Part = child.Part + merged.Part
+		n.Children[i] = merged
+	}
+}
+
+// hasName returns true if a route of pattern is named name in any method.
+func (n *Node) hasName(pattern, name string) bool {
 	for _, child := range n.Children {
-		if child.Part == part || child.IsWild {
+		for _, node := range n.nodes(child.Part, pattern) {
+			if node.Route.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// routes appends the routes registered below n to routes.
+func (n *Node) routes(routes []Route) []Route {
+	if !n.Route.IsBlank() {
+		routes = append(routes, n.Route)
+	}
+	for _, child := range n.Children {
+		routes = child.routes(routes)
+	}
+	return routes
+}
+
+// eachRoute calls fn with every route registered below n, so that fn can modify it.
+func (n *Node) eachRoute(fn func(*Route)) {
+	if !n.Route.IsBlank() {
+		fn(&n.Route)
+	}
+	for _, child := range n.Children {
+		child.eachRoute(fn)
+	}
+}
+
+// methodChild returns the child which holds the tree for method.
+// if create is true, the child is created when it does not exist.
+func (n *Node) methodChild(method string, create bool) *Node {
+	for _, child := range n.Children {
+		if child.Part == method {
+			if create {
+				return n.own(child)
+			}
+			return child
+		}
+	}
+	if !create {
+		return nil
+	}
+	child := &Node{Part: method}
+	n.Children = append(n.Children, child)
+	return child
+}
+
+// walkStatic walks down static children along part, splitting nodes
+// on the way if create is true, and returns the node which ends with part.
+// visit, if not nil, is called with every node on the way.
+func (n *Node) walkStatic(part string, create bool, visit func(*Node)) *Node {
+	for part != "" {
+		i := strings.IndexByte(n.indices, part[0])
+		if i < 0 {
+			if !create {
+				return nil
+			}
+			child := &Node{Part: part}
+			n.addStatic(child)
+			if visit != nil {
+				visit(child)
+			}
+			return child
+		}
+		child := n.Children[i]
+		l := commonPrefix(child.Part, part)
+		if l < len(child.Part) && !create {
+			return nil
+		}
+		if create {
+			child = n.own(child)
+		}
+		if l < len(child.Part) {
+			child.split(l)
+		}
+		n = child
+		if visit != nil {
+			visit(n)
+		}
+		part = part[l:]
+	}
+	return n
+}
+
+// addStatic adds child behind the existing static children.
+func (n *Node) addStatic(child *Node) {
+	i := len(n.indices)
+	n.Children = append(n.Children, nil)
+	copy(n.Children[i+1:], n.Children[i:])
+	n.Children[i] = child
+	n.indices += string(child.Part[0])
+}
+
+// split divides n at i. n keeps Part[:i] and the rest moves to a new child.
+func (n *Node) split(i int) {
+	child := &Node{
+		Part:     n.Part[i:],
+		Children: n.Children,
+		Route:    n.Route,
+		indices:  n.indices,
+	}
+	n.Part = n.Part[:i]
+	n.Children = []*Node{child}
+	n.Route = Route{}
+	n.indices = string(child.Part[0])
+}
+
+// addWild adds child to the wild children keeping the match priority.
+func (n *Node) addWild(child *Node) {
+	i := len(n.Children)
+	for i > len(n.indices) && child.wildBefore(n.Children[i-1]) {
+		i--
+	}
+	n.Children = append(n.Children, nil)
+	copy(n.Children[i+1:], n.Children[i:])
+	n.Children[i] = child
+}
+
+// wildRank returns the match priority of a wild node, lower is prior.
+// a named param with constraint precedes a named param, which precedes a catch-all,
+// and a suffixed param precedes the one spanning the segment.
+func (n *Node) wildRank() int {
+	rank := 0
+	switch {
+	case n.isCatchAll():
+		return 4
+	case n.constraint == nil:
+		rank = 2
+	}
+	if !n.suffixed {
+		rank++
+	}
+	return rank
+}
+
+// wildBefore reports whether n is tried before other.
+// wild nodes of the same rank are ordered by constraint and key,
+// so that the match does not depend on the registration order.
+func (n *Node) wildBefore(other *Node) bool {
+	if n.wildRank() != other.wildRank() {
+		return n.wildRank() < other.wildRank()
+	}
+	if c, o := wildConstraint(n.Part), wildConstraint(other.Part); c != o {
+		return c < o
+	}
+	return n.Key < other.Key
+}
+
+// wildChildren returns the wild children of n in match priority order.
+func (n *Node) wildChildren() []*Node {
+	return n.Children[len(n.indices):]
+}
+
+// wildChild returns the wild child of the same kind, constraint and suffixed as part.
+// it returns nil if n has no such child.
+func (n *Node) wildChild(part string, suffixed bool) *Node {
+	for _, child := range n.wildChildren() {
+		if child.isCatchAll() == isCatchAll(part) && wildConstraint(child.Part) == wildConstraint(part) &&
+			child.suffixed == suffixed {
 			return child
 		}
 	}
 	return nil
 }
 
+func (n *Node) isCatchAll() bool {
+	return n.IsWild && isCatchAll(n.Part)
+}
+
 func (n *Node) search(method, path string) Route {
-	parts := strings.Split("/"+method+path, "/")[1:]
+	route, _ := n.find(method, path, false)
+	return route
+}
 
-	pMap := map[string]string{}
-	for _, part := range parts {
-		child := n.matchChild(part)
-		if child == nil {
-			return Route{}
+// searchState holds the state of a lookup
+type searchState struct {
+	path   string
+	params map[string]string
+	// fold makes static parts match ignoring ASCII case, and
+	// canon is path with the static parts in the registered case
+	fold  bool
+	canon []byte
+}
+
+// find is search which matches static parts ignoring ASCII case if fold is true.
+// it also returns path with the static parts in the registered case.
+func (n *Node) find(method, path string, fold bool) (Route, string) {
+	n = n.methodChild(method, false)
+	if n == nil {
+		return Route{}, ""
+	}
+
+	s := searchState{path: path, fold: fold}
+	found := n.lookup(path, &s)
+	if found == nil {
+		return Route{}, ""
+	}
+	// copy the route so that the tree is not mutated by concurrent searches
+	route := found.Route
+	route.setPathParams(s.params)
+	if s.canon != nil {
+		return route, string(s.canon)
+	}
+	return route, path
+}
+
+// allowedMethods returns the sorted methods which have a route for path.
+func (n *Node) allowedMethods(path string, fold bool) []string {
+	var methods []string
+	for _, child := range n.Children {
+		if route, _ := n.find(child.Part, path, fold); !route.IsBlank() {
+			methods = append(methods, child.Part)
 		}
-		// if part is wild, set path param
-		if child.IsWild {
-			pMap[child.Key] = part
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// lookup returns the node which has a route for path below n.
+// children are tried in the priority order static, named param, catch-all,
+// and lookup backtracks to the next candidate when a branch dead-ends,
+// so the result does not depend on the registration order.
+func (n *Node) lookup(path string, s *searchState) *Node {
+	if path == "" && !n.Route.IsBlank() {
+		return n
+	}
+
+	if path != "" {
+		if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+			child := n.Children[i]
+			if strings.HasPrefix(path, child.Part) {
+				if found := child.lookup(path[len(child.Part):], s); found != nil {
+					return found
+				}
+			}
 		}
-		n = child
+		if s.fold {
+			if found := n.lookupFold(path, s); found != nil {
+				return found
+			}
+		}
+	}
+
+	if len(n.Children) == len(n.indices) {
+		return nil
+	}
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
 	}
-	n.Route.setPathParams(pMap)
-	return n.Route
+	for _, child := range n.wildChildren() {
+		if child.isCatchAll() {
+			// catch-all captures the remaining path including slashes
+			if found := child.capture(path, "", s); found != nil {
+				return found
+			}
+			continue
+		}
+		// param followed by a static part in the same segment, such as {name}.{ext}.
+		// the longest value is tried first.
+		if child.suffixed {
+			for k := end - 1; k > 0; k-- {
+				if !hasIndex(child.indices, path[k], s.fold) {
+					continue
+				}
+				if found := child.capture(path[:k], path[k:], s); found != nil {
+					return found
+				}
+			}
+			continue
+		}
+		// a named param does not capture an empty segment
+		if end == 0 {
+			continue
+		}
+		if found := child.capture(path[:end], path[end:], s); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// lookupFold looks up path below the static children of n which match path
+// only when ASCII case is ignored. the ones matching exactly are tried by lookup.
+// the children are tried in the order of the bytes matching in case, then of Part,
+// so the result does not depend on the registration order.
+func (n *Node) lookupFold(path string, s *searchState) *Node {
+	var candidates []*Node
+	for _, child := range n.Children[:len(n.indices)] {
+		if !strings.HasPrefix(path, child.Part) && hasPrefixFold(path, child.Part) {
+			candidates = append(candidates, child)
+		}
+	}
+	if len(candidates) > 1 {
+		slices.SortFunc(candidates, func(a, b *Node) int {
+			if c := matchingCase(path, b.Part) - matchingCase(path, a.Part); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Part, b.Part)
+		})
+	}
+
+	for _, child := range candidates {
+		if s.canon == nil {
+			s.canon = []byte(s.path)
+		}
+		i := len(s.path) - len(path)
+		copy(s.canon[i:], child.Part)
+		if found := child.lookup(path[len(child.Part):], s); found != nil {
+			return found
+		}
+		// restore the case of the request on dead end
+		copy(s.canon[i:], path[:len(child.Part)])
+	}
+	return nil
+}
+
+// capture matches value to the wild node n, and looks up rest below n.
+func (n *Node) capture(value, rest string, s *searchState) *Node {
+	if n.constraint != nil && !n.constraint(value) {
+		return nil
+	}
+	found := n.lookup(rest, s)
+	if found == nil {
+		return nil
+	}
+	// if part is wild, set path param
+	if s.params == nil {
+		s.params = map[string]string{}
+	}
+	s.params[n.Key] = value
+	return found
+}
+
+// hasIndex returns true if indices has c, ignoring ASCII case if fold is true
+func hasIndex(indices string, c byte, fold bool) bool {
+	if strings.IndexByte(indices, c) >= 0 {
+		return true
+	}
+	return fold && strings.IndexByte(indices, toggleCase(c)) >= 0
+}
+
+// hasPrefixFold returns true if s begins with prefix, ignoring ASCII case
+func hasPrefixFold(s, prefix string) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if s[i] != prefix[i] && toggleCase(s[i]) != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchingCase returns the number of bytes of prefix which s has in the same case
+func matchingCase(s, prefix string) int {
+	count := 0
+	for i := 0; i < len(prefix); i++ {
+		if s[i] == prefix[i] {
+			count++
+		}
+	}
+	return count
+}
+
+// toggleCase returns the other case of the ASCII letter c, or c itself if it is not a letter
+func toggleCase(c byte) byte {
+	switch {
+	case 'a' <= c && c <= 'z':
+		return c - 'a' + 'A'
+	case 'A' <= c && c <= 'Z':
+		return c - 'A' + 'a'
+	}
+	return c
+}
+
+// isSegmentStart returns true if i is the head of a path segment
+func isSegmentStart(pattern string, i int) bool {
+	return i > 0 && pattern[i-1] == '/'
+}
+
+// segment returns the path segment which starts at i
+func segment(pattern string, i int) string {
+	if end := strings.IndexByte(pattern[i:], '/'); end >= 0 {
+		return pattern[i : i+end]
+	}
+	return pattern[i:]
+}
+
+// nextWild returns the start and end index of the first wild part at or after i.
+// :name and *name span a whole segment, and {name} may be surrounded by
+// static parts in the segment, such as {name}.{ext}.
+// it returns -1, -1 if there is no wild part.
+func nextWild(pattern string, i int) (int, int) {
+	for j := i; j < len(pattern); j++ {
+		switch c := pattern[j]; {
+		case (c == ':' || c == '*') && isSegmentStart(pattern, j):
+			return j, j + len(segment(pattern, j))
+		case c == '{':
+			if end := closingBrace(pattern, j); end > 0 {
+				return j, end + 1
+			}
+		}
+	}
+	return -1, -1
+}
+
+// closingBrace returns the index of the brace which closes the one at i
+// in the same segment, or -1 if there is no such brace.
+func closingBrace(pattern string, i int) int {
+	depth := 0
+	for j := i; j < len(pattern) && pattern[j] != '/'; j++ {
+		switch pattern[j] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// expandOptional expands the optional params at the end of pattern, such as {page?},
+// into the patterns without and with them, shortest first.
+// e.g. /posts/{page?} is expanded into /posts and /posts/{page}.
+func expandOptional(pattern string) []string {
+	var b strings.Builder
+	var cuts []int
+	for i := 0; i < len(pattern); {
+		start, end := nextWild(pattern, i)
+		if start < 0 {
+			start, end = len(pattern), len(pattern)
+		}
+		static := pattern[i:start]
+		if len(cuts) > 0 && static != "/" {
+			panic("http: optional params must be at the end of pattern " + pattern)
+		}
+		b.WriteString(static)
+		if start == len(pattern) {
+			break
+		}
+
+		part := pattern[start:end]
+		if key := wildKey(part); part[0] == '{' && strings.HasSuffix(key, "?") {
+			if !isSegmentStart(pattern, start) || (end != len(pattern) && pattern[end] != '/') {
+				panic("http: optional param " + part + " must span a segment in pattern " + pattern)
+			}
+			cuts = append(cuts, b.Len()-1)
+			part = part[:len(key)] + part[len(key)+1:]
+		} else if len(cuts) > 0 {
+			panic("http: optional params must be at the end of pattern " + pattern)
+		}
+		b.WriteString(part)
+		i = end
+	}
+	if len(cuts) == 0 {
+		return []string{pattern}
+	}
+
+	full := b.String()
+	patterns := make([]string, 0, len(cuts)+1)
+	for _, cut := range cuts {
+		if cut == 0 {
+			patterns = append(patterns, "/")
+			continue
+		}
+		patterns = append(patterns, full[:cut])
+	}
+	return append(patterns, full)
+}
+
+// commonPrefix returns the length of the common prefix of a and b
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
 }
 
 // isWild returns true if pattern part is a wild part
@@ -71,6 +712,11 @@ func isWild(part string) bool {
 	return part[0] == ':' || part[0] == '*' || (part[0] == '{' && part[len(part)-1] == '}')
 }
 
+// isCatchAll returns true if pattern part is a catch-all part
+func isCatchAll(part string) bool {
+	return len(part) > 0 && part[0] == '*'
+}
+
 // wildKey returns the keyword of the wild from pattern part
 func wildKey(part string) string {
 	if !isWild(part) {
@@ -79,5 +725,39 @@ func wildKey(part string) string {
 	if part[0] == ':' || part[0] == '*' {
 		return part[1:]
 	}
-	return part[1 : len(part)-1]
+	key := part[1 : len(part)-1]
+	if i := strings.IndexByte(key, ':'); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// wildConstraint returns the constraint of the wild from pattern part,
+// such as `[0-9]+` of {id:[0-9]+} or `int` of {id:int}
+func wildConstraint(part string) string {
+	if !isWild(part) || part[0] != '{' {
+		return ""
+	}
+	if i := strings.IndexByte(part, ':'); i >= 0 {
+		return part[i+1 : len(part)-1]
+	}
+	return ""
+}
+
+// compileConstraint compiles the constraint of part to match a whole param value.
+// a type name in paramTypes is used as is, and others are compiled as regexp.
+// it returns nil if part has no constraint.
+func compileConstraint(part, pattern string) func(string) bool {
+	expr := wildConstraint(part)
+	if expr == "" {
+		return nil
+	}
+	if valid, ok := paramTypes[expr]; ok {
+		return valid
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		panic("http: invalid constraint " + part + " in pattern " + pattern + ": " + err.Error())
+	}
+	return re.MatchString
 }
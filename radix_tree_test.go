@@ -1,8 +1,10 @@
 package minimalmux
 
 import (
+	"fmt"
 	"net/http"
 	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -23,7 +25,7 @@ func TestNodeInsert(t *testing.T) {
 						Part: "GET",
 						Children: []*Node{
 							{
-								Part:     "",
+								Part:     "/",
 								Children: nil,
 								IsWild:   false,
 								Route:    Route{Method: http.MethodGet, Pattern: "/", HandlerFunc: dummyHandlerFunc},
@@ -47,7 +49,7 @@ func TestNodeInsert(t *testing.T) {
 						Part: "GET",
 						Children: []*Node{
 							{
-								Part:     "foo",
+								Part:     "/foo",
 								Children: nil,
 								IsWild:   false,
 								Route:    Route{Method: http.MethodGet, Pattern: "/foo", HandlerFunc: dummyHandlerFunc},
@@ -71,17 +73,10 @@ func TestNodeInsert(t *testing.T) {
 						Part: "GET",
 						Children: []*Node{
 							{
-								Part: "foo",
-								Children: []*Node{
-									{
-										Part:     "bar",
-										Children: nil,
-										IsWild:   false,
-										Route:    Route{Method: http.MethodGet, Pattern: "/foo/bar", HandlerFunc: dummyHandlerFunc},
-									},
-								},
-								IsWild: false,
-								Route:  Route{},
+								Part:     "/foo/bar",
+								Children: nil,
+								IsWild:   false,
+								Route:    Route{Method: http.MethodGet, Pattern: "/foo/bar", HandlerFunc: dummyHandlerFunc},
 							},
 						},
 						IsWild: false,
@@ -102,7 +97,7 @@ func TestNodeInsert(t *testing.T) {
 						Part: "GET",
 						Children: []*Node{
 							{
-								Part: "foo",
+								Part: "/foo/",
 								Children: []*Node{
 									{
 										Part:     ":id",
@@ -134,17 +129,24 @@ func TestNodeInsert(t *testing.T) {
 						Part: "GET",
 						Children: []*Node{
 							{
-								Part: "foo",
+								Part: "/foo/",
 								Children: []*Node{
 									{
 										Part: ":name",
 										Children: []*Node{
 											{
-												Part:     ":id",
-												Children: nil,
-												IsWild:   true,
-												Key:      "id",
-												Route:    Route{Method: http.MethodGet, Pattern: "/foo/:name/:id", HandlerFunc: dummyHandlerFunc},
+												Part: "/",
+												Children: []*Node{
+													{
+														Part:     ":id",
+														Children: nil,
+														IsWild:   true,
+														Key:      "id",
+														Route:    Route{Method: http.MethodGet, Pattern: "/foo/:name/:id", HandlerFunc: dummyHandlerFunc},
+													},
+												},
+												IsWild: false,
+												Route:  Route{},
 											},
 										},
 										Key:    "name",
@@ -169,12 +171,7 @@ func TestNodeInsert(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			n := &Node{}
-			n.insert(http.MethodGet, c.pattern, Route{
-				Method:      http.MethodGet,
-				Pattern:     c.pattern,
-				HandlerFunc: dummyHandlerFunc,
-			})
+			n := newTestTree(http.MethodGet, c.pattern)
 			if !deepEqualNode(t, n, c.expected) {
 				t.Errorf("\ngot %#v, \nwant %#v", n, c.expected)
 				t.Log("got: ==========================")
@@ -186,6 +183,81 @@ func TestNodeInsert(t *testing.T) {
 	}
 }
 
+func TestNodeInsertSplit(t *testing.T) {
+	n := newTestTree(http.MethodGet, "/foo", "/foobar", "/fizz", "/foo/:id")
+
+	expected := &Node{
+		Children: []*Node{
+			{
+				Part: "GET",
+				Children: []*Node{
+					{
+						Part: "/f",
+						Children: []*Node{
+							{
+								Part:  "oo",
+								Route: Route{Method: http.MethodGet, Pattern: "/foo"},
+								Children: []*Node{
+									{Part: "bar", Route: Route{Method: http.MethodGet, Pattern: "/foobar"}},
+									{
+										Part: "/",
+										Children: []*Node{
+											{Part: ":id", IsWild: true, Key: "id", Route: Route{Method: http.MethodGet, Pattern: "/foo/:id"}},
+										},
+									},
+								},
+							},
+							{Part: "izz", Route: Route{Method: http.MethodGet, Pattern: "/fizz"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	if !deepEqualNode(t, n, expected) {
+		t.Error("tree not equal")
+		t.Log("got: ==========================")
+		printChildren(t, n)
+		t.Log("want: ==========================")
+		printChildren(t, expected)
+	}
+	testEqual(t, n.Children[0].Children[0].indices, "oi")
+	testEqual(t, n.Children[0].Children[0].Children[0].indices, "b/")
+}
+
+func TestNodeInsertConflict(t *testing.T) {
+	cases := []struct {
+		name     string
+		patterns []string
+		panics   bool
+	}{
+		{name: "different param names", patterns: []string{"/users/:id", "/users/:uid/posts"}, panics: true},
+		{name: "different param names in braces", patterns: []string{"/users/{id}", "/users/{uid}"}, panics: true},
+		{name: "different catch-all names", patterns: []string{"/files/*path", "/files/*rest"}, panics: true},
+		{name: "same param name", patterns: []string{"/users/:id", "/users/:id/posts"}, panics: false},
+		{name: "same param name in other syntax", patterns: []string{"/users/:id", "/users/{id}/posts"}, panics: false},
+		{name: "param and catch-all", patterns: []string{"/files/:name", "/files/*path"}, panics: false},
+		{name: "different param names with same constraint", patterns: []string{"/posts/{id:int}", "/posts/{pid:int}"}, panics: true},
+		{name: "different param names with and without suffix", patterns: []string{"/files/{name}.{ext}", "/files/{id}"}, panics: false},
+		{name: "different suffixed param names", patterns: []string{"/files/{name}.{ext}", "/files/{base}.txt"}, panics: true},
+		{name: "different param names with other constraints", patterns: []string{"/posts/{id:int}", "/posts/{slug}"}, panics: false},
+		{name: "different names at other positions", patterns: []string{"/users/:id", "/posts/:postID"}, panics: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer func() {
+				err := recover()
+				if (err != nil) != c.panics {
+					t.Errorf("panic: %v, want panic: %v", err, c.panics)
+				}
+				t.Log(err)
+			}()
+			newTestTree(http.MethodGet, c.patterns...)
+		})
+	}
+}
+
 func TestNodeSearch(t *testing.T) {
 	dummyHandlerFunc := func(w http.ResponseWriter, r *http.Request) {}
 
@@ -229,24 +301,15 @@ func TestNodeSearch(t *testing.T) {
 				"name": "123",
 			}},
 		},
-		// NOTE: this case is not supported
-		// LIMITATION: some pattern which has same prefix is not supported another path param name
-		// {
-		// 	name:  "GET /foo/baz/123",
-		// 	input: input{method: http.MethodGet, path: "/foo/baz/123", pattern: "/foo/:name/:id"},
-		// 	expected: Route{Method: http.MethodGet, Pattern: "/foo/:name/:id", HandlerFunc: dummyHandlerFunc, PathParamMap: map[string]string{
-		// 		"name": "baz",
-		// 		"id":   "123",
-		// 	}},
-		// },
+		// NOTE: another path param name at the same position panics, see TestNodeInsertConflict
 	}
 
-	n := &Node{}
-	sm := &ServeMux{tree: n}
+	sm := &ServeMux{}
 	// register all routes
 	for _, c := range cases {
 		sm.handle(c.input.method, c.input.pattern, dummyHandlerFunc)
 	}
+	n := sm.tree.Load()
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -262,6 +325,547 @@ func TestNodeSearch(t *testing.T) {
 	}
 }
 
+func TestNodeSearchCatchAll(t *testing.T) {
+	n := newTestTree(http.MethodGet, "/static/*path", "/static/:name/info")
+
+	cases := []struct {
+		path     string
+		expected string
+		param    string
+	}{
+		{path: "/static/app.css", expected: "/static/*path", param: "app.css"},
+		{path: "/static/css/app.css", expected: "/static/*path", param: "css/app.css"},
+		{path: "/static/css/", expected: "/static/*path", param: "css/"},
+		{path: "/static/", expected: "/static/*path", param: ""},
+		{path: "/static/css/info", expected: "/static/:name/info"},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			r := n.search(http.MethodGet, c.path)
+			testEqual(t, r.Pattern, c.expected)
+			if c.param != "" || r.Pattern == "/static/*path" {
+				testEqual(t, r.PathParamMap["path"], c.param)
+			}
+		})
+	}
+
+	t.Run("panic if catch-all is not the last segment", func(t *testing.T) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Errorf("panic not occur")
+			}
+			t.Log(err)
+		}()
+		newTestTree(http.MethodGet, "/static/*path/info")
+	})
+}
+
+func TestNodeSearchConstraint(t *testing.T) {
+	n := newTestTree(http.MethodGet,
+		"/orders/{id}/items",
+		"/orders/{id:[0-9]+}",
+		"/orders/{id:[a-z]{2}-[0-9]+}",
+		"/orders/new",
+		"/items/{id:[0-9]+}",
+		"/posts/{id:int}",
+		"/posts/{date:[0-9]{4}-[0-9]{2}-[0-9]{2}}",
+		"/posts/{slug}",
+	)
+
+	cases := []struct {
+		path     string
+		expected string
+		id       string
+	}{
+		{path: "/orders/123", expected: "/orders/{id:[0-9]+}", id: "123"},
+		{path: "/orders/ab-123", expected: "/orders/{id:[a-z]{2}-[0-9]+}", id: "ab-123"},
+		{path: "/orders/new", expected: "/orders/new"},
+		{path: "/orders/abc", expected: ""},
+		{path: "/orders/abc/items", expected: "/orders/{id}/items", id: "abc"},
+		{path: "/orders/123/items", expected: "/orders/{id}/items", id: "123"},
+		{path: "/items/123", expected: "/items/{id:[0-9]+}", id: "123"},
+		{path: "/items/12a", expected: ""},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			r := n.search(http.MethodGet, c.path)
+			testEqual(t, r.Pattern, c.expected)
+			testEqual(t, r.PathParamMap["id"], c.id)
+		})
+	}
+
+	// constrained siblings with other names fall through to each other
+	namedCases := []struct {
+		path     string
+		expected string
+		params   map[string]string
+	}{
+		{path: "/posts/42", expected: "/posts/{id:int}", params: map[string]string{"id": "42"}},
+		{path: "/posts/2024-01-02", expected: "/posts/{date:[0-9]{4}-[0-9]{2}-[0-9]{2}}", params: map[string]string{"date": "2024-01-02"}},
+		{path: "/posts/hello-world", expected: "/posts/{slug}", params: map[string]string{"slug": "hello-world"}},
+	}
+	for _, c := range namedCases {
+		t.Run(c.path, func(t *testing.T) {
+			r := n.search(http.MethodGet, c.path)
+			testEqual(t, r.Pattern, c.expected)
+			testEqual(t, len(r.PathParamMap), len(c.params))
+			for k, v := range c.params {
+				testEqual(t, r.PathParamMap[k], v)
+			}
+		})
+	}
+
+	// constrained siblings of the same rank are tried in the same order for every registration order
+	for _, patterns := range [][]string{
+		{"/p/{id:int}", "/p/{num:[0-9]+}"},
+		{"/p/{num:[0-9]+}", "/p/{id:int}"},
+	} {
+		n := newTestTree(http.MethodGet, patterns...)
+		t.Run(fmt.Sprintf("%v", patterns), func(t *testing.T) {
+			testEqual(t, n.search(http.MethodGet, "/p/42").Pattern, "/p/{num:[0-9]+}")
+			testEqual(t, n.search(http.MethodGet, "/p/-42").Pattern, "/p/{id:int}")
+		})
+	}
+
+	t.Run("panic if constraint is invalid", func(t *testing.T) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Errorf("panic not occur")
+			}
+			t.Log(err)
+		}()
+		newTestTree(http.MethodGet, "/orders/{id:[0-9}")
+	})
+}
+
+func TestNodeSearchInfix(t *testing.T) {
+	n := newTestTree(http.MethodGet,
+		"/report-{year:int}.csv",
+		"/report-latest.csv",
+		"/files/{name}",
+		"/files/{name}.{ext}",
+		"/files/{name}.{ext}/raw",
+		"/v{major}.{minor}/status",
+		"/reports/{id}",
+		"/reports/{name}.{ext}",
+	)
+
+	cases := []struct {
+		path     string
+		expected string
+		params   map[string]string
+	}{
+		{path: "/report-2024.csv", expected: "/report-{year:int}.csv", params: map[string]string{"year": "2024"}},
+		{path: "/report-latest.csv", expected: "/report-latest.csv", params: map[string]string{}},
+		{path: "/report-abc.csv", expected: ""},
+		{path: "/report-2024.json", expected: ""},
+		{path: "/files/readme", expected: "/files/{name}", params: map[string]string{"name": "readme"}},
+		{path: "/files/app.css", expected: "/files/{name}.{ext}", params: map[string]string{"name": "app", "ext": "css"}},
+		{path: "/files/archive.tar.gz", expected: "/files/{name}.{ext}", params: map[string]string{"name": "archive.tar", "ext": "gz"}},
+		{path: "/files/app.css/raw", expected: "/files/{name}.{ext}/raw", params: map[string]string{"name": "app", "ext": "css"}},
+		{path: "/files/.env", expected: "/files/{name}", params: map[string]string{"name": ".env"}},
+		{path: "/v1.2/status", expected: "/v{major}.{minor}/status", params: map[string]string{"major": "1", "minor": "2"}},
+		{path: "/reports/42", expected: "/reports/{id}", params: map[string]string{"id": "42"}},
+		{path: "/reports/q1.pdf", expected: "/reports/{name}.{ext}", params: map[string]string{"name": "q1", "ext": "pdf"}},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			r := n.search(http.MethodGet, c.path)
+			testEqual(t, r.Pattern, c.expected)
+			if c.params == nil {
+				return
+			}
+			testEqual(t, len(r.PathParamMap), len(c.params))
+			for k, v := range c.params {
+				testEqual(t, r.PathParamMap[k], v)
+			}
+		})
+	}
+
+	t.Run("panic if wildcards are adjacent", func(t *testing.T) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Errorf("panic not occur")
+			}
+			t.Log(err)
+		}()
+		newTestTree(http.MethodGet, "/files/{name}{ext}")
+	})
+}
+
+func TestNodeFindFold(t *testing.T) {
+	patterns := []string{
+		"/api/users",
+		"/api/users/{id}",
+		"/api/Users/me",
+		"/api/{resource}/count",
+		"/files/{name}.CSV",
+	}
+	cases := []struct {
+		path      string
+		fold      bool
+		expected  string
+		canonical string
+		params    map[string]string
+	}{
+		{path: "/API/Users", fold: false, expected: ""},
+		{path: "/API/Users", fold: true, expected: "/api/users", canonical: "/api/users", params: map[string]string{}},
+		{path: "/Api/USERS/AbC", fold: true, expected: "/api/users/{id}", canonical: "/api/users/AbC", params: map[string]string{"id": "AbC"}},
+		{path: "/api/users/me", fold: true, expected: "/api/users/{id}", canonical: "/api/users/me", params: map[string]string{"id": "me"}},
+		{path: "/api/Users/me", fold: true, expected: "/api/Users/me", canonical: "/api/Users/me", params: map[string]string{}},
+		// the casing closest to the request is preferred
+		{path: "/API/USERS/ME", fold: true, expected: "/api/Users/me", canonical: "/api/Users/me", params: map[string]string{}},
+		{path: "/api/Users/ME", fold: true, expected: "/api/Users/me", canonical: "/api/Users/me", params: map[string]string{}},
+		{path: "/api/users/ME", fold: true, expected: "/api/users/{id}", canonical: "/api/users/ME", params: map[string]string{"id": "ME"}},
+		// the case of the dead-ended static branch is not kept
+		{path: "/API/USERSX/COUNT", fold: true, expected: "/api/{resource}/count", canonical: "/api/USERSX/count", params: map[string]string{"resource": "USERSX"}},
+		{path: "/files/Report.csv", fold: true, expected: "/files/{name}.CSV", canonical: "/files/Report.CSV", params: map[string]string{"name": "Report"}},
+	}
+
+	// every registration order must give the same result
+	orders := [][]int{{0, 1, 2, 3, 4}, {4, 3, 2, 1, 0}}
+	for _, order := range orders {
+		n := &Node{}
+		for _, i := range order {
+			insertTestRoutes(n, http.MethodGet, patterns[i])
+		}
+		for _, c := range cases {
+			t.Run(fmt.Sprintf("%v %s", order, c.path), func(t *testing.T) {
+				r, canonical := n.find(http.MethodGet, c.path, c.fold)
+				testEqual(t, r.Pattern, c.expected)
+				testEqual(t, canonical, c.canonical)
+				if c.params == nil {
+					return
+				}
+				testEqual(t, len(r.PathParamMap), len(c.params))
+				for k, v := range c.params {
+					testEqual(t, r.PathParamMap[k], v)
+				}
+			})
+		}
+	}
+}
+
+func TestNodeSearchPriority(t *testing.T) {
+	patterns := []string{
+		"/users/*rest",
+		"/users/:id",
+		"/users/:id/posts",
+		"/users/me",
+		"/users/me/settings",
+	}
+	cases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/users/me", expected: "/users/me"},
+		{path: "/users/123", expected: "/users/:id"},
+		{path: "/users/me/settings", expected: "/users/me/settings"},
+		{path: "/users/me/posts", expected: "/users/:id/posts"}, // static branch dead-ends
+		{path: "/users/mean", expected: "/users/:id"},
+		{path: "/users/123/likes", expected: "/users/*rest"},
+		{path: "/users/me/settings/extra", expected: "/users/*rest"},
+	}
+
+	// every registration order must give the same result
+	orders := [][]int{{0, 1, 2, 3, 4}, {4, 3, 2, 1, 0}, {1, 3, 0, 4, 2}}
+	for _, order := range orders {
+		n := &Node{}
+		for _, i := range order {
+			insertTestRoutes(n, http.MethodGet, patterns[i])
+		}
+		for _, c := range cases {
+			t.Run(fmt.Sprintf("%v %s", order, c.path), func(t *testing.T) {
+				r := n.search(http.MethodGet, c.path)
+				testEqual(t, r.Pattern, c.expected)
+			})
+		}
+	}
+}
+
+func TestNodeSearchConcurrent(t *testing.T) {
+	n := newTestTree(http.MethodGet, "/users/:id", "/users/:id/posts/:postID", "/users/me")
+
+	var wg sync.WaitGroup
+	for g := 0; g < 64; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				id := fmt.Sprintf("%d-%d", g, i)
+				r := n.search(http.MethodGet, "/users/"+id+"/posts/"+id)
+				if r.PathParamMap["id"] != id || r.PathParamMap["postID"] != id {
+					t.Errorf("param mismatch. got: %v, want: %s", r.PathParamMap, id)
+					return
+				}
+				r = n.search(http.MethodGet, "/users/"+id)
+				if r.PathParamMap["id"] != id {
+					t.Errorf("param mismatch. got: %v, want: %s", r.PathParamMap, id)
+					return
+				}
+				if r = n.search(http.MethodGet, "/users/me"); len(r.PathParamMap) != 0 {
+					t.Errorf("unexpected params. got: %v", r.PathParamMap)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestNodeClone(t *testing.T) {
+	n := newTestTree(http.MethodGet, "/foo/:id")
+
+	c := n.clone()
+	insertTestRoutes(c, http.MethodGet, "/foobar")
+	insertTestRoutes(c, http.MethodPost, "/foo/:id")
+
+	// the original tree is not affected by inserts into the clone
+	testEqual(t, n.search(http.MethodGet, "/foobar").Pattern, "")
+	testEqual(t, n.search(http.MethodPost, "/foo/1").Pattern, "")
+	testEqual(t, n.search(http.MethodGet, "/foo/1").Pattern, "/foo/:id")
+	testEqual(t, n.Children[0].Children[0].Part, "/foo/")
+
+	testEqual(t, c.search(http.MethodGet, "/foobar").Pattern, "/foobar")
+	testEqual(t, c.search(http.MethodPost, "/foo/1").Pattern, "/foo/:id")
+	testEqual(t, c.search(http.MethodGet, "/foo/1").Pattern, "/foo/:id")
+}
+
+func TestNodeCopy(t *testing.T) {
+	n := newTestTree(http.MethodGet, "/foo", "/foo/:id", "/fizz")
+	insertTestRoutes(n, http.MethodPost, "/foo")
+	original := n.clone()
+
+	c := n.copy()
+	insertTestRoutes(c, http.MethodGet, "/foobar")
+	insertTestRoutes(c, http.MethodGet, "/foo/:id/bar")
+	for _, node := range c.modifiable(http.MethodGet, "/fizz") {
+		node.Route.Name = "fizz"
+	}
+	testEqual(t, c.remove(http.MethodPost, "/foo"), true)
+
+	// the original tree is not affected by modifications of the copy
+	if !deepEqualNode(t, n, original) {
+		t.Error("original tree is modified")
+		printChildren(t, n)
+	}
+	testEqual(t, n.search(http.MethodGet, "/fizz").Name, "")
+	testEqual(t, n.search(http.MethodPost, "/foo").Pattern, "/foo")
+
+	testEqual(t, c.search(http.MethodGet, "/foobar").Pattern, "/foobar")
+	testEqual(t, c.search(http.MethodGet, "/foo/1/bar").Pattern, "/foo/:id/bar")
+	testEqual(t, c.search(http.MethodGet, "/fizz").Name, "fizz")
+	testEqual(t, c.search(http.MethodPost, "/foo").Pattern, "")
+
+	// untouched branches are shared with the original tree
+	c = n.copy()
+	insertTestRoutes(c, http.MethodGet, "/fizzbuzz")
+	testEqual(t, c.walk(http.MethodGet, "/foo", false) == n.walk(http.MethodGet, "/foo", false), true)
+	testEqual(t, c.walk(http.MethodGet, "/fizz", false) == n.walk(http.MethodGet, "/fizz", false), false)
+	testEqual(t, c.methodChild(http.MethodPost, false) == n.methodChild(http.MethodPost, false), true)
+
+	// removal copies only the nodes on the way, including the merged ones
+	c = n.copy()
+	testEqual(t, c.remove(http.MethodGet, "/foo/:id"), true)
+	testEqual(t, c.walk(http.MethodGet, "/fizz", false) == n.walk(http.MethodGet, "/fizz", false), true)
+	testEqual(t, c.methodChild(http.MethodPost, false) == n.methodChild(http.MethodPost, false), true)
+	testEqual(t, c.remove(http.MethodGet, "/foo"), true)
+	testEqual(t, c.search(http.MethodGet, "/fizz").Pattern, "/fizz")
+	if !deepEqualNode(t, n, original) {
+		t.Error("original tree is modified by remove")
+		printChildren(t, n)
+	}
+}
+
+func TestNodeRemove(t *testing.T) {
+	n := newTestTree(http.MethodGet, "/foo", "/foobar", "/fizz", "/foo/:id")
+	insertTestRoutes(n, http.MethodPost, "/foo")
+
+	testEqual(t, n.remove(http.MethodGet, "/unknown"), false)
+	testEqual(t, n.remove(http.MethodGet, "/fo"), false)
+
+	testEqual(t, n.remove(http.MethodGet, "/foo/:id"), true)
+	testEqual(t, n.remove(http.MethodGet, "/fizz"), true)
+	testEqual(t, n.remove(http.MethodPost, "/foo"), true)
+	testEqual(t, n.remove(http.MethodPost, "/foo"), false)
+
+	expected := &Node{
+		Children: []*Node{
+			{
+				Part: "GET",
+				Children: []*Node{
+					{
+						Part:  "/foo",
+						Route: Route{Method: http.MethodGet, Pattern: "/foo"},
+						Children: []*Node{
+							{Part: "bar", Route: Route{Method: http.MethodGet, Pattern: "/foobar"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	if !deepEqualNode(t, n, expected) {
+		t.Error("tree not equal")
+		t.Log("got: ==========================")
+		printChildren(t, n)
+		t.Log("want: ==========================")
+		printChildren(t, expected)
+	}
+	testEqual(t, n.Children[0].indices, "/")
+	testEqual(t, n.Children[0].Children[0].indices, "b")
+	testEqual(t, n.search(http.MethodGet, "/foobar").Pattern, "/foobar")
+	testEqual(t, n.search(http.MethodGet, "/foo/1").Pattern, "")
+
+	// removed routes can be registered again
+	insertTestRoutes(n, http.MethodGet, "/fizz")
+	testEqual(t, n.search(http.MethodGet, "/fizz").Pattern, "/fizz")
+	testEqual(t, n.search(http.MethodGet, "/foobar").Pattern, "/foobar")
+}
+
+func benchmarkRoutes() []string {
+	routes := []string{"/", "/healthcheck"}
+	for i := 0; i < 100; i++ {
+		prefix := fmt.Sprintf("/api/v1/resource%d", i)
+		routes = append(routes,
+			prefix,
+			prefix+"/:id",
+			prefix+"/:id/children",
+			prefix+"/:id/children/:childID",
+			prefix+"/search",
+			prefix+"/stats",
+		)
+	}
+	return routes
+}
+
+func benchmarkTree() *Node {
+	return newTestTree(http.MethodGet, benchmarkRoutes()...)
+}
+
+func BenchmarkNodeSearchStatic(b *testing.B) {
+	n := benchmarkTree()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n.search(http.MethodGet, "/api/v1/resource99/stats")
+	}
+}
+
+func BenchmarkNodeSearchParam(b *testing.B) {
+	n := benchmarkTree()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n.search(http.MethodGet, "/api/v1/resource99/123/children/456")
+	}
+}
+
+func BenchmarkNodeSearchNotFound(b *testing.B) {
+	n := benchmarkTree()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n.search(http.MethodGet, "/api/v2/unknown")
+	}
+}
+
+func TestExpandOptional(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		expected []string
+		panics   bool
+	}{
+		{pattern: "/posts", expected: []string{"/posts"}},
+		{pattern: "/posts/{page?}", expected: []string{"/posts", "/posts/{page}"}},
+		{pattern: "/posts/{page?:int}", expected: []string{"/posts", "/posts/{page:int}"}},
+		{pattern: "/{lang?}", expected: []string{"/", "/{lang}"}},
+		{pattern: "/archive/{year?}/{month?}", expected: []string{"/archive", "/archive/{year}", "/archive/{year}/{month}"}},
+		{pattern: "/users/:id/{tab?}", expected: []string{"/users/:id", "/users/:id/{tab}"}},
+		{pattern: "/posts/{page?}/comments", panics: true},
+		{pattern: "/archive/{year?}/:month", panics: true},
+		{pattern: "/files/{name?}.txt", panics: true},
+	}
+	for _, c := range cases {
+		t.Run(c.pattern, func(t *testing.T) {
+			defer func() {
+				err := recover()
+				if (err != nil) != c.panics {
+					t.Errorf("panic: %v, want panic: %v", err, c.panics)
+				}
+			}()
+			got := expandOptional(c.pattern)
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("\ngot %#v, \nwant %#v", got, c.expected)
+			}
+		})
+	}
+}
+
+func TestNextWild(t *testing.T) {
+	cases := []struct {
+		pattern     string
+		i           int
+		expectStart int
+		expectEnd   int
+	}{
+		{pattern: "/foo", i: 0, expectStart: -1, expectEnd: -1},
+		{pattern: "/foo/:id", i: 0, expectStart: 5, expectEnd: 8},
+		{pattern: "/foo/*path", i: 0, expectStart: 5, expectEnd: 10},
+		{pattern: "/foo/a:b", i: 0, expectStart: -1, expectEnd: -1},
+		{pattern: "/foo/{id}/bar", i: 0, expectStart: 5, expectEnd: 9},
+		{pattern: "/report-{year}.csv", i: 0, expectStart: 8, expectEnd: 14},
+		{pattern: "/files/{name}.{ext}", i: 13, expectStart: 14, expectEnd: 19},
+		{pattern: "/orders/{id:[0-9]{3}}", i: 0, expectStart: 8, expectEnd: 21},
+		{pattern: "/foo/{id", i: 0, expectStart: -1, expectEnd: -1},
+	}
+	for _, c := range cases {
+		start, end := nextWild(c.pattern, c.i)
+		testEqual(t, start, c.expectStart)
+		testEqual(t, end, c.expectEnd)
+	}
+}
+
+func TestWildKey(t *testing.T) {
+	cases := []struct {
+		part             string
+		expectKey        string
+		expectConstraint string
+	}{
+		{part: ":id", expectKey: "id"},
+		{part: "*path", expectKey: "path"},
+		{part: "{id}", expectKey: "id"},
+		{part: "{id:[0-9]+}", expectKey: "id", expectConstraint: "[0-9]+"},
+		{part: "{id:[0-9]{3}}", expectKey: "id", expectConstraint: "[0-9]{3}"},
+		{part: "foo", expectKey: ""},
+	}
+	for _, c := range cases {
+		testEqual(t, wildKey(c.part), c.expectKey)
+		testEqual(t, wildConstraint(c.part), c.expectConstraint)
+	}
+}
+
+// newTestTree returns a tree which has a route for method and every pattern.
+func newTestTree(method string, patterns ...string) *Node {
+	n := &Node{}
+	insertTestRoutes(n, method, patterns...)
+	return n
+}
+
+// insertTestRoutes inserts a route for method and every pattern into n.
+func insertTestRoutes(n *Node, method string, patterns ...string) {
+	for _, pattern := range patterns {
+		n.insert(method, pattern, Route{
+			Method:      method,
+			Pattern:     pattern,
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {},
+		})
+	}
+}
+
 func printChildren(t *testing.T, n *Node) {
 	t.Helper()
 	t.Logf("%#v", n)
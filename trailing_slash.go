@@ -0,0 +1,54 @@
+package minimalmux
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TrailingSlash is the policy for a request path which differs from
+// a registered pattern only in its trailing slash, such as /users/ for /users.
+type TrailingSlash int
+
+const (
+	// TrailingSlashStrict treats /users and /users/ as different paths.
+	TrailingSlashStrict TrailingSlash = iota
+	// TrailingSlashRedirect redirects to the registered form,
+	// with 301 for GET and HEAD, and 308 for the other methods.
+	TrailingSlashRedirect
+	// TrailingSlashEquivalent serves the registered form without redirect.
+	TrailingSlashEquivalent
+)
+
+// toggleTrailingSlash adds a trailing slash to path, or removes it.
+// it returns false for the root path.
+func toggleTrailingSlash(path string) (string, bool) {
+	if path == "" || path == "/" {
+		return "", false
+	}
+	if strings.HasSuffix(path, "/") {
+		return path[:len(path)-1], true
+	}
+	return path + "/", true
+}
+
+// redirectTo replies to r with a redirect to path, keeping the query.
+// path is relative to the mount prefix of r, if any, and is already escaped if escaped is true.
+func redirectTo(path string, escaped bool) http.HandlerFunc {
+	if !escaped {
+		path = (&url.URL{Path: path}).EscapedPath()
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		prefix := (&url.URL{Path: mountPath(r)}).EscapedPath()
+		// a location beginning with // would be taken as another host
+		location := "/" + strings.TrimLeft(prefix+path, "/")
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, location, code)
+	}
+}
@@ -0,0 +1,119 @@
+package minimalmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeMuxTrailingSlash(t *testing.T) {
+	newMux := func(policy TrailingSlash) *ServeMux {
+		mux := NewServeMux(WithTrailingSlash(policy))
+		mux.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("users"))
+		})
+		mux.Post("/users", testHandler)
+		mux.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("user"))
+		})
+		mux.Get("/docs/", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("docs"))
+		})
+		mux.Get("/both", testHandler)
+		mux.Get("/both/", testHandler)
+		mux.Put("/items/{id}", testHandler)
+		return mux
+	}
+
+	tcs := []struct {
+		policy         TrailingSlash
+		method         string
+		path           string
+		expectStatus   int
+		expectLocation string
+		expectBody     string
+	}{
+		{policy: TrailingSlashStrict, method: http.MethodGet, path: "/users", expectStatus: http.StatusOK},
+		{policy: TrailingSlashStrict, method: http.MethodGet, path: "/users/", expectStatus: http.StatusNotFound},
+		{policy: TrailingSlashStrict, method: http.MethodGet, path: "/docs", expectStatus: http.StatusNotFound},
+		{policy: TrailingSlashStrict, method: http.MethodGet, path: "/users/1", expectStatus: http.StatusOK, expectBody: "user"},
+
+		{policy: TrailingSlashRedirect, method: http.MethodGet, path: "/users", expectStatus: http.StatusOK},
+		{policy: TrailingSlashRedirect, method: http.MethodGet, path: "/users/", expectStatus: http.StatusMovedPermanently, expectLocation: "/users"},
+		{policy: TrailingSlashRedirect, method: http.MethodGet, path: "/users/?page=2", expectStatus: http.StatusMovedPermanently, expectLocation: "/users?page=2"},
+		{policy: TrailingSlashRedirect, method: http.MethodHead, path: "/users/", expectStatus: http.StatusMovedPermanently, expectLocation: "/users"},
+		{policy: TrailingSlashRedirect, method: http.MethodPost, path: "/users/", expectStatus: http.StatusPermanentRedirect, expectLocation: "/users"},
+		{policy: TrailingSlashRedirect, method: http.MethodGet, path: "/users/1/", expectStatus: http.StatusMovedPermanently, expectLocation: "/users/1"},
+		{policy: TrailingSlashRedirect, method: http.MethodGet, path: "/docs", expectStatus: http.StatusMovedPermanently, expectLocation: "/docs/"},
+		{policy: TrailingSlashRedirect, method: http.MethodPut, path: "/items/1/", expectStatus: http.StatusPermanentRedirect, expectLocation: "/items/1"},
+		{policy: TrailingSlashRedirect, method: http.MethodGet, path: "/both/", expectStatus: http.StatusOK},
+		{policy: TrailingSlashRedirect, method: http.MethodGet, path: "/unknown/", expectStatus: http.StatusNotFound},
+
+		{policy: TrailingSlashEquivalent, method: http.MethodGet, path: "/users/", expectStatus: http.StatusOK, expectBody: "users"},
+		{policy: TrailingSlashEquivalent, method: http.MethodGet, path: "/docs", expectStatus: http.StatusOK, expectBody: "docs"},
+		{policy: TrailingSlashEquivalent, method: http.MethodHead, path: "/docs", expectStatus: http.StatusOK},
+		{policy: TrailingSlashEquivalent, method: http.MethodDelete, path: "/users/", expectStatus: http.StatusMethodNotAllowed},
+		{policy: TrailingSlashEquivalent, method: http.MethodGet, path: "/unknown", expectStatus: http.StatusNotFound},
+	}
+	for _, tc := range tcs {
+		mux := newMux(tc.policy)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.expectStatus {
+			t.Errorf("%d %s %s: Status code not equal. got: %d, want: %d", tc.policy, tc.method, tc.path, w.Code, tc.expectStatus)
+		}
+		if location := w.Header().Get("Location"); location != tc.expectLocation {
+			t.Errorf("%d %s %s: Location not equal. got: %q, want: %q", tc.policy, tc.method, tc.path, location, tc.expectLocation)
+		}
+		if tc.expectBody != "" && w.Body.String() != tc.expectBody {
+			t.Errorf("%d %s %s: Response body not equal. got: %q, want: %q", tc.policy, tc.method, tc.path, w.Body.String(), tc.expectBody)
+		}
+	}
+}
+
+func TestServeMuxTrailingSlashMounted(t *testing.T) {
+	child := NewServeMux(WithTrailingSlash(TrailingSlashRedirect))
+	child.Get("/users", testHandler)
+	mux := NewServeMux()
+	mux.Mount("/api/{version}", child)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/users/", nil))
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("Status code not equal. got: %d, want: %d", w.Code, http.StatusMovedPermanently)
+	}
+	if location := w.Header().Get("Location"); location != "/api/v1/users" {
+		t.Errorf("Location not equal. got: %q, want: %q", location, "/api/v1/users")
+	}
+}
+
+func TestServeMuxTrailingSlashOpenRedirect(t *testing.T) {
+	mux := NewServeMux(WithTrailingSlash(TrailingSlashRedirect))
+	mux.Get("//evil.com", testHandler)
+
+	// must not redirect to the host evil.com
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "//evil.com/", nil))
+	if location := w.Header().Get("Location"); location != "/evil.com" {
+		t.Errorf("Location not equal. got: %q, want: %q", location, "/evil.com")
+	}
+}
+
+func TestToggleTrailingSlash(t *testing.T) {
+	tcs := []struct {
+		path     string
+		expected string
+		ok       bool
+	}{
+		{path: "/", ok: false},
+		{path: "", ok: false},
+		{path: "/users", expected: "/users/", ok: true},
+		{path: "/users/", expected: "/users", ok: true},
+	}
+	for _, tc := range tcs {
+		got, ok := toggleTrailingSlash(tc.path)
+		if got != tc.expected || ok != tc.ok {
+			t.Errorf("%q: got %q %v, want %q %v", tc.path, got, ok, tc.expected, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package minimalmux
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// RouteRef refers to routes registered by Get, Post, etc.
+// It is used to configure the routes after registration.
+type RouteRef struct {
+	mux     *ServeMux
+	methods []string
+	pattern string
+}
+
+// Name names the route, so that its URL can be built by ServeMux.URL.
+// It panics if name is already used by another pattern, or if the route is removed.
+// The previous name of the route is forgotten.
+func (rr *RouteRef) Name(name string) *RouteRef {
+	if name == "" {
+		panic("http: empty route name")
+	}
+
+	sm := rr.mux
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if pattern, ok := sm.names[name]; ok && pattern != rr.pattern {
+		panic("http: duplicated route name " + name + " for " + pattern + " and " + rr.pattern)
+	}
+
+	tree := sm.loadTree().copy()
+	var nodes []*Node
+	for _, m := range rr.methods {
+		nodes = append(nodes, tree.modifiable(m, rr.pattern)...)
+	}
+	if len(nodes) == 0 {
+		panic("http: route " + rr.pattern + " is not registered")
+	}
+	var oldNames []string
+	for _, n := range nodes {
+		if n.Route.Name != "" && n.Route.Name != name {
+			oldNames = append(oldNames, n.Route.Name)
+		}
+		n.Route.Name = name
+	}
+	sm.tree.Store(tree)
+
+	// forget the previous names when no method of the pattern keeps them
+	for _, old := range oldNames {
+		if !tree.hasName(rr.pattern, old) {
+			delete(sm.names, old)
+		}
+	}
+	if sm.names == nil {
+		sm.names = map[string]string{}
+	}
+	sm.names[name] = rr.pattern
+	return rr
+}
+
+// URL builds the path of the route named name.
+// pairs are the path params as key and value alternately, e.g. URL("user.show", "id", "42").
+// values are escaped, and it returns an error if a param is missing or unknown.
+func (sm *ServeMux) URL(name string, pairs ...string) (string, error) {
+	sm.mu.Lock()
+	pattern, ok := sm.names[name]
+	sm.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("http: no route named %s", name)
+	}
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("http: odd number of params for route %s", name)
+	}
+
+	params := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		params[pairs[i]] = pairs[i+1]
+	}
+
+	// the longest pattern whose params are all given is used,
+	// so that optional params are omitted when they are not given
+	patterns := expandOptional(pattern)
+	var path string
+	var keys map[string]bool
+	var err error
+	for i := len(patterns) - 1; i >= 0; i-- {
+		if path, keys, err = buildPath(patterns[i], params); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w for route %s", err, name)
+	}
+
+	for i := 0; i < len(pairs); i += 2 {
+		if !keys[pairs[i]] {
+			return "", fmt.Errorf("http: unknown param %s for route %s", pairs[i], name)
+		}
+	}
+	return path, nil
+}
+
+// buildPath replaces the wild parts of pattern with params.
+// it returns the keys of the replaced params.
+func buildPath(pattern string, params map[string]string) (string, map[string]bool, error) {
+	var b strings.Builder
+	keys := map[string]bool{}
+	for i := 0; i < len(pattern); {
+		start, end := nextWild(pattern, i)
+		if start < 0 {
+			b.WriteString(pattern[i:])
+			break
+		}
+		b.WriteString(pattern[i:start])
+
+		part := pattern[start:end]
+		key := wildKey(part)
+		value, ok := params[key]
+		if !ok {
+			return "", nil, fmt.Errorf("http: missing param %s", key)
+		}
+		keys[key] = true
+		b.WriteString(escapeParam(value, isCatchAll(part)))
+		i = end
+	}
+	return b.String(), keys, nil
+}
+
+// escapeParam escapes value as a path param.
+// slashes are kept in the value of a catch-all.
+func escapeParam(value string, catchAll bool) string {
+	if !catchAll {
+		return url.PathEscape(value)
+	}
+	parts := strings.Split(value, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
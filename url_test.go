@@ -0,0 +1,128 @@
+package minimalmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeMuxURL(t *testing.T) {
+	mux := NewServeMux()
+	mux.Get("/", testHandler).Name("home")
+	mux.Get("/users/:id", testHandler).Name("user.show")
+	mux.Get("/users/{id}/posts/:postID", testHandler).Name("user.post")
+	mux.Get("/static/*path", testHandler).Name("static")
+	mux.Get("/files/{name}.{ext}", testHandler).Name("file")
+	mux.Get("/archive/{year?:int}/{month?}", testHandler).Name("archive")
+	mux.Group("/api", func(g *Group) {
+		g.Get("/items/:id", testHandler).Name("api.item")
+	})
+
+	tcs := []struct {
+		name      string
+		pairs     []string
+		expectURL string
+		expectErr bool
+	}{
+		{name: "home", expectURL: "/"},
+		{name: "user.show", pairs: []string{"id", "42"}, expectURL: "/users/42"},
+		{name: "user.show", pairs: []string{"id", "a b/c"}, expectURL: "/users/a%20b%2Fc"},
+		{name: "user.post", pairs: []string{"postID", "2", "id", "1"}, expectURL: "/users/1/posts/2"},
+		{name: "static", pairs: []string{"path", "css/app 1.css"}, expectURL: "/static/css/app%201.css"},
+		{name: "api.item", pairs: []string{"id", "7"}, expectURL: "/api/items/7"},
+		{name: "file", pairs: []string{"name", "app", "ext", "css"}, expectURL: "/files/app.css"},
+		{name: "archive", expectURL: "/archive"},
+		{name: "archive", pairs: []string{"year", "2024"}, expectURL: "/archive/2024"},
+		{name: "archive", pairs: []string{"year", "2024", "month", "1"}, expectURL: "/archive/2024/1"},
+		{name: "archive", pairs: []string{"month", "1"}, expectErr: true},
+		{name: "unknown", expectErr: true},
+		{name: "user.show", expectErr: true},
+		{name: "user.show", pairs: []string{"id"}, expectErr: true},
+		{name: "user.show", pairs: []string{"id", "42", "extra", "1"}, expectErr: true},
+	}
+	for _, tc := range tcs {
+		got, err := mux.URL(tc.name, tc.pairs...)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s %v: unexpected error: %v", tc.name, tc.pairs, err)
+			continue
+		}
+		if got != tc.expectURL {
+			t.Errorf("%s %v: URL not equal. got: %s, want: %s", tc.name, tc.pairs, got, tc.expectURL)
+		}
+	}
+}
+
+func TestRouteRefName(t *testing.T) {
+	t.Run("name is available from GetRoute", func(t *testing.T) {
+		var name string
+		mux := NewServeMux()
+		mux.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+			route, _ := GetRoute(r)
+			name = route.Name
+		}).Name("user.show")
+
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+		testEqual(t, name, "user.show")
+	})
+
+	t.Run("name is forgotten when the route is removed", func(t *testing.T) {
+		mux := NewServeMux()
+		mux.Get("/users/:id", testHandler).Name("user.show")
+		mux.Remove(http.MethodGet, "/users/:id")
+
+		if _, err := mux.URL("user.show", "id", "1"); err == nil {
+			t.Error("error not occur")
+		}
+	})
+
+	t.Run("previous name is forgotten when the route is renamed", func(t *testing.T) {
+		mux := NewServeMux()
+		mux.Get("/x", testHandler).Name("a").Name("b")
+
+		if _, err := mux.URL("a"); err == nil {
+			t.Error("error not occur")
+		}
+		u, err := mux.URL("b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		testEqual(t, u, "/x")
+	})
+
+	t.Run("previous name is kept while another method has it", func(t *testing.T) {
+		mux := NewServeMux()
+		mux.Get("/x", testHandler).Name("a")
+		mux.Post("/x", testHandler).Name("a").Name("b")
+
+		if _, err := mux.URL("a"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("panic if the route is removed", func(t *testing.T) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Errorf("panic not occur")
+			}
+			t.Log(err)
+		}()
+		mux := NewServeMux()
+		ref := mux.Get("/x", testHandler)
+		mux.Remove(http.MethodGet, "/x")
+		ref.Name("x")
+	})
+
+	t.Run("panic if name is duplicated", func(t *testing.T) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Errorf("panic not occur")
+			}
+			t.Log(err)
+		}()
+		mux := NewServeMux()
+		mux.Get("/users/:id", testHandler).Name("user")
+		mux.Get("/users", testHandler).Name("user")
+	})
+}